@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	dbComments "Skillfactory-APIGateway/comments/storage"
+	"Skillfactory-APIGateway/pkg/opml"
+	"Skillfactory-APIGateway/pkg/retention"
+	"Skillfactory-APIGateway/pkg/service"
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+// Справка по командам CLI.
+const usage = `Использование:
+  gonews                         запуск сервера
+  gonews opml import <файл>      импорт лент из OPML, "-" - стандартный ввод
+  gonews opml export [файл]      экспорт лент в OPML, по умолчанию в стандартный вывод
+  gonews prune [--dry-run]       удаление новостей старше retention.days из config.json,
+                                 с --dry-run - только подсчёт`
+
+// runCommand выполняет команду CLI. Команды работают с той же БД,
+// что и сервер, но не обновляют её схему.
+func runCommand(args []string) error {
+	switch args[0] {
+	case "opml":
+		if len(args) < 2 {
+			return errors.New(usage)
+		}
+		switch args[1] {
+		case "import":
+			if len(args) != 3 {
+				return errors.New(usage)
+			}
+			return importOPML(args[2])
+		case "export":
+			path := "-"
+			if len(args) == 3 {
+				path = args[2]
+			}
+			return exportOPML(path)
+		}
+	case "prune":
+		switch {
+		case len(args) == 1:
+			return prune(false)
+		case len(args) == 2 && args[1] == "--dry-run":
+			return prune(true)
+		}
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return nil
+	}
+	return errors.New(usage)
+}
+
+// importOPML добавляет в БД ленты из файла OPML. Сервер начнёт
+// опрашивать их после перезапуска; чтобы ленты опрашивались сразу,
+// загрузите файл через /api/v1/admin/feeds/import.
+func importOPML(path string) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	sources, err := opml.Parse(r)
+	if err != nil {
+		return err
+	}
+	db, err := storage.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	res, err := service.New(db, nil).ImportSources(context.Background(), sources)
+	if err != nil {
+		return err
+	}
+	for _, src := range res.Created {
+		fmt.Printf("добавлена лента %d: %s (%s)\n", src.ID, src.Name, src.URL)
+	}
+	for _, url := range res.Skipped {
+		fmt.Printf("лента уже есть: %s\n", url)
+	}
+	fmt.Printf("добавлено %d, пропущено %d\n", len(res.Created), len(res.Skipped))
+	return nil
+}
+
+// exportOPML записывает ленты из БД в файл OPML.
+func exportOPML(path string) error {
+	db, err := storage.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	sources, err := db.Sources(context.Background())
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return opml.Write(w, "GoNews", sources)
+}
+
+// prune удаляет устаревшие новости и комментарии к ним по политике
+// хранения из config.json и выводит, сколько удалено.
+func prune(dryRun bool) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.Retention.Days < 1 {
+		return errors.New("срок хранения новостей retention.days не задан в config.json")
+	}
+	db, err := storage.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	comments, err := dbComments.Connect()
+	if err != nil {
+		return err
+	}
+	defer comments.Pool.Close()
+
+	r, err := retention.New(cfg.Retention, db, comments).Prune(context.Background(), dryRun)
+	if err != nil && dryRun {
+		return err
+	}
+	if err != nil {
+		// часть пачек могла быть удалена до ошибки
+		return fmt.Errorf("очистка прервана, успели удалить новостей %d, комментариев %d: %w",
+			r.News, r.Comments, err)
+	}
+	verb := "удалено"
+	if dryRun {
+		verb = "будет удалено"
+	}
+	fmt.Printf("новости старше %s: %s новостей %d, комментариев %d\n",
+		r.Before.Format("2006-01-02 15:04"), verb, r.News, r.Comments)
+	return nil
+}
@@ -2,25 +2,60 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
-	"time"
+	"os"
 
 	commentStorege "Skillfactory-APIGateway/comments/storage"
 	"Skillfactory-APIGateway/pkg/api"
+	"Skillfactory-APIGateway/pkg/grpcapi"
+	"Skillfactory-APIGateway/pkg/ingest"
+	"Skillfactory-APIGateway/pkg/retention"
 	"Skillfactory-APIGateway/pkg/rss"
+	"Skillfactory-APIGateway/pkg/scheduler"
+	"Skillfactory-APIGateway/pkg/service"
 	"Skillfactory-APIGateway/pkg/storage"
+	"Skillfactory-APIGateway/pkg/webhooks"
 )
 
 // конфигурация приложения
 type config struct {
-	URLS   []string `json:"rss"`
-	Period int      `json:"request_period"`
+	URLS       []string `json:"rss"`
+	Period     int      `json:"request_period"`
+	GRPCListen string   `json:"grpc_listen"`
+	AdminToken string   `json:"admin_token"`
+	// параметры конвейера сбора новостей
+	Ingest ingest.Config `json:"ingest"`
+	// параметры загрузки лент
+	Fetch rss.FetcherConfig `json:"fetch"`
+	// срок хранения новостей и параметры их очистки
+	Retention retention.Config `json:"retention"`
+}
+
+// loadConfig читает файл конфигурации config.json.
+func loadConfig() (config, error) {
+	var c config
+	b, err := ioutil.ReadFile("./config.json")
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
 }
 
 func main() {
+	// команды CLI: gonews opml import feeds.opml
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// инициализация зависимостей приложения
 	db, err := storage.New()
 	if err != nil {
@@ -32,43 +67,69 @@ func main() {
 		log.Fatal(err)
 	}
 	defer dbComment.Pool.Close()
-	api := api.New(db, dbComment)
+	hooks, err := webhooks.New(db.Pool)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// чтение и раскодирование файла конфигурации
-	b, err := ioutil.ReadFile("./config.json")
+	config, err := loadConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
-	var config config
-	err = json.Unmarshal(b, &config)
+	// токен администратора можно не хранить в файле
+	if token := os.Getenv("GONEWS_ADMIN_TOKEN"); token != "" {
+		config.AdminToken = token
+	}
+
+	svc := service.New(db, dbComment)
+	api := api.New(svc, hooks, config.AdminToken)
+
+	// доставка веб-хуков о новых новостях и комментариях
+	go webhooks.NewDispatcher(hooks).Run(context.Background(), svc.SubscribeAll())
+
+	if config.Period < 1 {
+		config.Period = storage.DefaultInterval
+	}
+	// ленты из файла конфигурации попадают в БД только при первом запуске,
+	// когда лент ещё нет; дальше лентами управляют через /api/v1/admin/feeds
+	err = db.SeedSources(context.Background(), config.URLS, config.Period)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	// запуск парсинга новостей в отдельном потоке
-	// для каждой ссылки
-	chPosts := make(chan []storage.Post)
-	chErrs := make(chan error)
-	for _, url := range config.URLS {
-		go parseURL(url, chPosts, chErrs, config.Period)
+	sources, err := db.Sources(context.Background())
+	if err != nil {
+		log.Fatal(err)
 	}
+	// конвейер сбора новостей: загрузка, разбор, нормализация, фильтрация,
+	// отсев повторов и запись в БД с рассылкой новых новостей подписчикам
+	fetcher := rss.NewFetcher(config.Fetch)
+	pipeline := ingest.New(config.Ingest, fetcher.Fetch, rss.ParseBytes, db, svc.Publish)
+	go pipeline.Run(context.Background())
+	svc.SetPipeline(pipeline)
+	// планирование опроса каждой включённой ленты, опросы выполняет конвейер
+	sch := scheduler.New(context.Background(), pipeline.Process)
+	for _, src := range sources {
+		sch.Set(src)
+	}
+	svc.SetScheduler(sch)
 
-	// запись потока новостей в БД
-	go func() {
-		for posts := range chPosts {
-			db.StoreNews(posts)
-			if err != nil {
-				log.Println(err)
-			}
-		}
-	}()
+	// очистка устаревших новостей и комментариев к ним
+	go retention.New(config.Retention, db, dbComment).Run(context.Background())
 
-	// обработка потока ошибок
-	go func() {
-		for err := range chErrs {
-			log.Println("ошибка:", err)
+	// запуск gRPC-сервера
+	if config.GRPCListen != "" {
+		lis, err := net.Listen("tcp", config.GRPCListen)
+		if err != nil {
+			log.Fatal(err)
 		}
-	}()
+		go func() {
+			log.Println("gRPC-сервер запущен на", config.GRPCListen)
+			if err := grpcapi.New(svc).Serve(lis); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
 
 	// запуск веб-сервера с API и приложением
 	err = http.ListenAndServe(":80", api.Router())
@@ -76,17 +137,3 @@ func main() {
 		log.Fatal(err)
 	}
 }
-
-// Асинхронное чтение потока RSS. Раскодированные
-// новости и ошибки пишутся в каналы.
-func parseURL(url string, posts chan<- []storage.Post, errs chan<- error, period int) {
-	for {
-		news, err := rss.Parse(url)
-		if err != nil {
-			errs <- err
-			continue
-		}
-		posts <- news
-		time.Sleep(time.Minute * time.Duration(period))
-	}
-}
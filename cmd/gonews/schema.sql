@@ -1,8 +1,70 @@
-DROP TABLE IF EXISTS news;
+CREATE TABLE IF NOT EXISTS sources (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL UNIQUE,
+    link TEXT NOT NULL DEFAULT '',
+    category TEXT NOT NULL DEFAULT '',
+    interval INTEGER NOT NULL DEFAULT 25,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    tag_rules JSONB NOT NULL DEFAULT '[]'
+);
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS interval INTEGER NOT NULL DEFAULT 25;
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS enabled BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '';
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS tag_rules JSONB NOT NULL DEFAULT '[]';
 CREATE TABLE IF NOT EXISTS news (
     id SERIAL PRIMARY KEY,
     title TEXT NOT NULL DEFAULT 'empty',
     content TEXT NOT NULL DEFAULT 'empty',
     pub_time INTEGER DEFAULT extract (epoch from now()),
-    link TEXT NOT NULL UNIQUE
-);
\ No newline at end of file
+    link TEXT NOT NULL UNIQUE,
+    source_id INTEGER REFERENCES sources(id) ON DELETE SET NULL,
+    guid TEXT NOT NULL DEFAULT '',
+    author TEXT NOT NULL DEFAULT '',
+    categories TEXT[] NOT NULL DEFAULT '{}',
+    enclosures JSONB NOT NULL DEFAULT '[]',
+    image TEXT NOT NULL DEFAULT '',
+    raw_html TEXT NOT NULL DEFAULT '',
+    content_html TEXT NOT NULL DEFAULT '',
+    summary TEXT NOT NULL DEFAULT '',
+    canonical_url TEXT NOT NULL DEFAULT '',
+    simhash BIGINT NOT NULL DEFAULT 0,
+    duplicate_of INTEGER REFERENCES news(id) ON DELETE SET NULL,
+    comments INTEGER NOT NULL DEFAULT 0
+);
+ALTER TABLE news ADD COLUMN IF NOT EXISTS source_id INTEGER REFERENCES sources(id) ON DELETE SET NULL;
+ALTER TABLE news ADD COLUMN IF NOT EXISTS guid TEXT NOT NULL DEFAULT '';
+ALTER TABLE news ADD COLUMN IF NOT EXISTS author TEXT NOT NULL DEFAULT '';
+ALTER TABLE news ADD COLUMN IF NOT EXISTS categories TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE news ADD COLUMN IF NOT EXISTS enclosures JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE news ADD COLUMN IF NOT EXISTS image TEXT NOT NULL DEFAULT '';
+ALTER TABLE news ADD COLUMN IF NOT EXISTS raw_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE news ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE news ADD COLUMN IF NOT EXISTS summary TEXT NOT NULL DEFAULT '';
+ALTER TABLE news ADD COLUMN IF NOT EXISTS canonical_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE news ADD COLUMN IF NOT EXISTS simhash BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE news ADD COLUMN IF NOT EXISTS duplicate_of INTEGER REFERENCES news(id) ON DELETE SET NULL;
+ALTER TABLE news ADD COLUMN IF NOT EXISTS comments INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS news_pub_time_idx ON news (pub_time);
+CREATE INDEX IF NOT EXISTS news_source_id_idx ON news (source_id);
+CREATE INDEX IF NOT EXISTS news_canonical_url_idx ON news (canonical_url);
+CREATE INDEX IF NOT EXISTS news_guid_idx ON news (guid);
+CREATE INDEX IF NOT EXISTS news_duplicate_of_idx ON news (duplicate_of);
+CREATE TABLE IF NOT EXISTS tags (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+CREATE TABLE IF NOT EXISTS news_tags (
+    news_id INTEGER NOT NULL REFERENCES news(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (news_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS news_tags_tag_id_idx ON news_tags (tag_id);
+CREATE TABLE IF NOT EXISTS news_archive (
+    id BIGSERIAL PRIMARY KEY,
+    news_id INTEGER NOT NULL,
+    pub_time BIGINT NOT NULL,
+    data JSONB NOT NULL,
+    archived_at BIGINT NOT NULL DEFAULT extract (epoch from now())
+);
+CREATE INDEX IF NOT EXISTS news_archive_pub_time_idx ON news_archive (pub_time);
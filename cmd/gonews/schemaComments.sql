@@ -1,11 +1,49 @@
-DROP TABLE IF EXISTS comments;
 CREATE TABLE IF NOT EXISTS comments (
     id SERIAL PRIMARY KEY,
     news_id INT,
     content TEXT NOT NULL DEFAULT 'empty',
-    pub_time INTEGER DEFAULT extract (epoch from now())
+    pub_time INTEGER DEFAULT extract (epoch from now()),
+    deleted_at BIGINT,
+    deleted_by TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT ''
 );
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_at BIGINT;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS reason TEXT NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS comments_archive (
+    id BIGSERIAL PRIMARY KEY,
+    comment_id INTEGER NOT NULL,
+    news_id INTEGER NOT NULL,
+    data JSONB NOT NULL,
+    archived_at BIGINT NOT NULL DEFAULT extract (epoch from now())
+);
+CREATE TABLE IF NOT EXISTS comment_audit (
+    id BIGSERIAL PRIMARY KEY,
+    comment_id INTEGER NOT NULL,
+    news_id INTEGER NOT NULL DEFAULT 0,
+    action TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL DEFAULT '',
+    previous TEXT NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL DEFAULT extract (epoch from now())
+);
+CREATE INDEX IF NOT EXISTS comment_audit_comment_id_idx ON comment_audit (comment_id);
+CREATE INDEX IF NOT EXISTS comment_audit_news_id_idx ON comment_audit (news_id);
+-- журнал только пополняется
+CREATE OR REPLACE FUNCTION comment_audit_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'журнал действий с комментариями нельзя изменять';
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS comment_audit_append_only ON comment_audit;
+CREATE TRIGGER comment_audit_append_only BEFORE UPDATE OR DELETE ON comment_audit
+    FOR EACH ROW EXECUTE FUNCTION comment_audit_append_only();
+DROP TRIGGER IF EXISTS comment_audit_no_truncate ON comment_audit;
+CREATE TRIGGER comment_audit_no_truncate BEFORE TRUNCATE ON comment_audit
+    FOR EACH STATEMENT EXECUTE FUNCTION comment_audit_append_only();
 
-INSERT INTO comments(news_id,content)  VALUES (1,'тестовый комментарий 1');
-
-INSERT INTO comments(news_id,content)  VALUES (1,'тестовый комментарий 2');
\ No newline at end of file
+-- тестовые комментарии добавляются только в пустую таблицу
+INSERT INTO comments(news_id,content)
+SELECT 1, c FROM unnest(ARRAY['тестовый комментарий 1', 'тестовый комментарий 2']) AS c
+WHERE NOT EXISTS (SELECT 1 FROM comments);
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Действия с коментами в журнале.
+const (
+	ActionAdd    = "add"
+	ActionEdit   = "edit"
+	ActionDelete = "delete"
+)
+
+// ActorRetention - автор удалений при очистке устаревших новостей.
+const ActorRetention = "retention"
+
+// AuditEntry - запись журнала действий с коментами. Журнал только
+// пополняется: изменить или удалить запись не даёт триггер в БД.
+type AuditEntry struct {
+	ID        int64  `json:"id"`
+	CommentID int    `json:"commentID"`
+	NewsID    int    `json:"newsID"`
+	Action    string `json:"action"`
+	// Actor - кто выполнил действие.
+	Actor string `json:"actor"`
+	// Reason - причина удаления.
+	Reason string `json:"reason,omitempty"`
+	// Content - текст комента после действия, для удаления - удалённый текст.
+	Content string `json:"content"`
+	// Previous - текст комента до изменения.
+	Previous  string `json:"previous,omitempty"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// AuditFilter - условия отбора записей журнала, нулевые поля не учитываются.
+type AuditFilter struct {
+	CommentID int
+	NewsID    int
+	Action    string
+	// Limit - наибольшее число записей.
+	Limit int
+}
+
+// audit добавляет запись в журнал в транзакции действия.
+func audit(ctx context.Context, tx pgx.Tx, e AuditEntry) error {
+	_, err := tx.Exec(ctx, `
+	INSERT INTO comment_audit (comment_id, news_id, action, actor, reason, content, previous)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		e.CommentID, e.NewsID, e.Action, e.Actor, e.Reason, e.Content, e.Previous)
+	return err
+}
+
+// Audit возвращает записи журнала, начиная с последних.
+func (db *DB) Audit(ctx context.Context, f AuditFilter) ([]AuditEntry, error) {
+	if f.Limit < 1 {
+		return nil, fmt.Errorf("%w: число записей должно быть больше нуля", ErrInvalidArgument)
+	}
+	rows, err := db.Pool.Query(ctx, `
+	SELECT id, comment_id, news_id, action, actor, reason, content, previous, created_at
+	FROM comment_audit
+	WHERE ($1 = 0 OR comment_id = $1) AND ($2 = 0 OR news_id = $2) AND ($3 = '' OR action = $3)
+	ORDER BY id DESC
+	LIMIT $4`, f.CommentID, f.NewsID, f.Action, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		err = rows.Scan(&e.ID, &e.CommentID, &e.NewsID, &e.Action, &e.Actor, &e.Reason,
+			&e.Content, &e.Previous, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
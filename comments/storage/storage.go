@@ -10,9 +10,16 @@ import (
 	"log"
 	"os"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Ошибки хранилища, по которым API выбирает статус ответа.
+var (
+	ErrNotFound        = errors.New("комментарий не найден")
+	ErrInvalidArgument = errors.New("некорректный комментарий")
+)
+
 // База данных.
 type DB struct {
 	Pool *pgxpool.Pool
@@ -21,7 +28,7 @@ type DB struct {
 // конфигурация подключения к PostgreSQL
 type sqlPostgres struct {
 	Host           string `json:"host"`
-	portPostgres   int    `json:"portPostgres"`
+	PortPostgres   int    `json:"portPostgres"`
 	UserDB         string `json:"userDB"`
 	Password       string `json:"password"`
 	DBnamePostges  string `json:"dbnamePostges"`
@@ -33,10 +40,44 @@ type Comment struct {
 	NewsID  int    `json:"newsID,omitempty"`
 	Content string `json:"content,omitempty"`
 	PubTime int64  `json:"pubTime,omitempty"`
+	// Deleted - комент удалён, вместо его текста API выводит RemovedContent.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// Текст удалённого комента. Удалённые коменты остаются в списках,
+// чтобы не нарушать порядок обсуждения.
+const RemovedContent = "[removed]"
+
+// Столбцы комента для scanComment.
+const commentColumns = "id, COALESCE(news_id, 0), content, pub_time, deleted_at IS NOT NULL"
+
+// scanComment читает комент из строки результата запроса по commentColumns.
+func scanComment(row pgx.Row) (Comment, error) {
+	var c Comment
+	err := row.Scan(&c.ID, &c.NewsID, &c.Content, &c.PubTime, &c.Deleted)
+	return c, err
 }
 
 // Запись в БД новых новостей
 func New() (*DB, error) {
+	db, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	// Выполнение SQL-скрипта
+	if err := db.initSchema(); err != nil {
+		return nil, fmt.Errorf("ошибка инициализации схемы комментариев: %v", err)
+	} else {
+		fmt.Println("создана БД комментариев")
+	}
+
+	return db, nil
+}
+
+// Connect подключается к БД комментариев без инициализации схемы,
+// комментарии сохраняются. Используется командами CLI.
+func Connect() (*DB, error) {
 	// Чтение конфигурации базы данных файла
 	b, err := ioutil.ReadFile("./sqlPostgres.json")
 	if err != nil {
@@ -57,18 +98,7 @@ func New() (*DB, error) {
 	if err != nil {
 		return nil, err
 	}
-	db := DB{
-		Pool: pool,
-	}
-
-	// Выполнение SQL-скрипта
-	if err := db.initSchema(); err != nil {
-		return nil, fmt.Errorf("ошибка инициализации схемы комментариев: %v", err)
-	} else {
-		fmt.Println("создана БД комментариев")
-	}
-
-	return &db, nil
+	return &DB{Pool: pool}, nil
 }
 
 // initSchema выполняет SQL-скрипт из файла для инициализации БД
@@ -88,16 +118,15 @@ func (db *DB) initSchema() error {
 }
 
 // AllComments выводит все коменты.
-func (db *DB) AllComments(newsID int) ([]Comment, error) {
-	rows, err := db.Pool.Query(context.Background(), "SELECT * FROM comments WHERE news_id = $1;", newsID)
+func (db *DB) AllComments(ctx context.Context, newsID int) ([]Comment, error) {
+	rows, err := db.Pool.Query(ctx, "SELECT "+commentColumns+" FROM comments WHERE news_id = $1 ORDER BY id;", newsID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var comments []Comment
 	for rows.Next() {
-		var c Comment
-		err = rows.Scan(&c.ID, &c.NewsID, &c.Content, &c.PubTime)
+		c, err := scanComment(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -106,22 +135,162 @@ func (db *DB) AllComments(newsID int) ([]Comment, error) {
 	return comments, rows.Err()
 }
 
-// AddComment добавляет коменты.
-func (db *DB) AddComment(c Comment) error {
-	_, err := db.Pool.Exec(context.Background(),
-		"INSERT INTO comments (news_id,content) VALUES ($1,$2);", c.NewsID, c.Content)
+// CommentsByNews выводит коменты к нескольким новостям одним запросом.
+// Результат сгруппирован по id новости.
+func (db *DB) CommentsByNews(ctx context.Context, newsIDs []int) (map[int][]Comment, error) {
+	rows, err := db.Pool.Query(ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE news_id = ANY($1) ORDER BY id;", newsIDs)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	defer rows.Close()
+	comments := make(map[int][]Comment, len(newsIDs))
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments[c.NewsID] = append(comments[c.NewsID], c)
+	}
+	return comments, rows.Err()
 }
 
-// DeleteComment удаляет коменты.
-func (db *DB) DeleteComment(c Comment) error {
-	_, err := db.Pool.Exec(context.Background(),
-		"DELETE FROM comments WHERE id=$1;", c.ID)
+// AddComment добавляет комент от имени actor, записывает это
+// в журнал и возвращает id комента.
+func (db *DB) AddComment(ctx context.Context, c Comment, actor string) (int, error) {
+	if c.NewsID < 1 {
+		return 0, fmt.Errorf("%w: не указана новость", ErrInvalidArgument)
+	}
+	if c.Content == "" {
+		return 0, fmt.Errorf("%w: пустой текст", ErrInvalidArgument)
+	}
+	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return nil
+	defer tx.Rollback(ctx)
+	var id int
+	err = tx.QueryRow(ctx,
+		"INSERT INTO comments (news_id,content) VALUES ($1,$2) RETURNING id;", c.NewsID, c.Content).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	err = audit(ctx, tx, AuditEntry{CommentID: id, NewsID: c.NewsID, Action: ActionAdd, Actor: actor, Content: c.Content})
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit(ctx)
+}
+
+// EditComment заменяет текст комента c.ID на c.Content от имени actor,
+// записывает это в журнал вместе с прежним текстом и возвращает комент.
+// Удалённые коменты не изменяются.
+func (db *DB) EditComment(ctx context.Context, c Comment, actor string) (Comment, error) {
+	if c.Content == "" {
+		return Comment{}, fmt.Errorf("%w: пустой текст", ErrInvalidArgument)
+	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return Comment{}, err
+	}
+	defer tx.Rollback(ctx)
+	var previous string
+	err = tx.QueryRow(ctx,
+		"SELECT content FROM comments WHERE id = $1 AND deleted_at IS NULL FOR UPDATE;", c.ID).Scan(&previous)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Comment{}, ErrNotFound
+	}
+	if err != nil {
+		return Comment{}, err
+	}
+	c, err = scanComment(tx.QueryRow(ctx,
+		"UPDATE comments SET content = $2 WHERE id = $1 RETURNING "+commentColumns+";", c.ID, c.Content))
+	if err != nil {
+		return Comment{}, err
+	}
+	err = audit(ctx, tx, AuditEntry{CommentID: c.ID, NewsID: c.NewsID, Action: ActionEdit, Actor: actor,
+		Content: c.Content, Previous: previous})
+	if err != nil {
+		return Comment{}, err
+	}
+	return c, tx.Commit(ctx)
+}
+
+// DeleteComment помечает комент удалённым от имени actor по причине
+// reason, записывает это в журнал и возвращает id его новости.
+// Текст комента сохраняется в БД, но больше не выводится.
+func (db *DB) DeleteComment(ctx context.Context, c Comment, actor, reason string) (int, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	err = tx.QueryRow(ctx, `
+	UPDATE comments SET deleted_at = extract (epoch from now()), deleted_by = $2, reason = $3
+	WHERE id = $1 AND deleted_at IS NULL
+	RETURNING COALESCE(news_id, 0), content;`, c.ID, actor, reason).Scan(&c.NewsID, &c.Content)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	err = audit(ctx, tx, AuditEntry{CommentID: c.ID, NewsID: c.NewsID, Action: ActionDelete, Actor: actor,
+		Reason: reason, Content: c.Content})
+	if err != nil {
+		return 0, err
+	}
+	return c.NewsID, tx.Commit(ctx)
+}
+
+// CommentCounts возвращает число коментов к каждой новости.
+func (db *DB) CommentCounts(ctx context.Context) (map[int]int, error) {
+	rows, err := db.Pool.Query(ctx,
+		"SELECT COALESCE(news_id, 0), count(*) FROM comments GROUP BY 1;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[int]int)
+	for rows.Next() {
+		var newsID, n int
+		if err := rows.Scan(&newsID, &n); err != nil {
+			return nil, err
+		}
+		counts[newsID] = n
+	}
+	return counts, rows.Err()
+}
+
+// RemoveByNews удаляет коменты к новостям и возвращает их число.
+// При archive коменты сначала копируются в comments_archive.
+// Удаление ещё не удалённых коментов записывается в журнал.
+func (db *DB) RemoveByNews(ctx context.Context, newsIDs []int, archive bool) (int, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	// коменты без новости хранятся с news_id NULL, в newsIDs это 0
+	const where = "COALESCE(news_id, 0) = ANY($1)"
+	if archive {
+		_, err = tx.Exec(ctx, `
+		INSERT INTO comments_archive (comment_id, news_id, data)
+		SELECT id, COALESCE(news_id, 0), to_jsonb(c) FROM comments c WHERE `+where, newsIDs)
+		if err != nil {
+			return 0, err
+		}
+	}
+	_, err = tx.Exec(ctx, `
+	INSERT INTO comment_audit (comment_id, news_id, action, actor, reason, content)
+	SELECT id, COALESCE(news_id, 0), $2, $3, $4, content FROM comments
+	WHERE deleted_at IS NULL AND `+where, newsIDs, ActionDelete, ActorRetention, "новость удалена")
+	if err != nil {
+		return 0, err
+	}
+	tag, err := tx.Exec(ctx, "DELETE FROM comments WHERE "+where, newsIDs)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), tx.Commit(ctx)
 }
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	dbComments "Skillfactory-APIGateway/comments/storage"
+	"Skillfactory-APIGateway/pkg/service"
+	"Skillfactory-APIGateway/pkg/webhooks"
+)
+
+// Число записей журналов доставки и действий с комментариями
+// по умолчанию и максимальное.
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 500
+)
+
+// Middleware для проверки токена администратора в заголовке
+// Authorization: Bearer <token>. Если токен не задан в конфигурации,
+// административные методы недоступны.
+func (api *API) adminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if api.adminToken == "" {
+			writeError(w, r, http.StatusForbidden, codeForbidden, "admin API is disabled", nil)
+			return
+		}
+		if !api.isAdmin(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gonews-admin"`)
+			writeError(w, r, http.StatusUnauthorized, codeUnauthorized, "invalid admin token", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAdmin проверяет токен администратора в запросе.
+func (api *API) isAdmin(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return api.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(api.adminToken)) == 1
+}
+
+// Middleware, которое сохраняет в контексте автора действий
+// с комментариями для журнала: "admin" для запросов с токеном
+// администратора, иначе адрес клиента.
+func (api *API) actorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := "admin"
+		if !api.isAdmin(r) {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			actor = "ip:" + host
+		}
+		next.ServeHTTP(w, r.WithContext(service.WithActor(r.Context(), actor)))
+	})
+}
+
+// Журнал действий с комментариями: GET /api/v1/admin/comments/audit,
+// с фильтрами comment_id, news_id и action.
+func (api *API) commentAuditHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	q := r.URL.Query()
+	f := dbComments.AuditFilter{Limit: defaultDeliveriesLimit, Action: q.Get("action")}
+	for _, p := range []struct {
+		name string
+		max  int
+		dst  *int
+	}{{"comment_id", 0, &f.CommentID}, {"news_id", 0, &f.NewsID}, {"limit", maxDeliveriesLimit, &f.Limit}} {
+		s := q.Get(p.name)
+		if s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || (p.max > 0 && n > p.max) {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid "+p.name+" parameter", nil)
+			return
+		}
+		*p.dst = n
+	}
+	switch f.Action {
+	case "", dbComments.ActionAdd, dbComments.ActionEdit, dbComments.ActionDelete:
+	default:
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid action parameter", nil)
+		return
+	}
+	entries, err := api.svc.CommentAudit(r.Context(), f)
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(entries)
+}
+
+// Создание подписки на веб-хуки: POST /api/v1/admin/webhooks.
+// Ключ подписи возвращается только в ответе на создание.
+func (api *API) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var sub webhooks.Subscription
+	err := json.NewDecoder(r.Body).Decode(&sub)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "request body is not valid JSON", nil)
+		return
+	}
+	sub, err = api.hooks.Create(r.Context(), sub)
+	if err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sub)
+}
+
+// Список подписок: GET /api/v1/admin/webhooks.
+func (api *API) webhooksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	subs, err := api.hooks.List(r.Context())
+	if err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(subs)
+}
+
+// Удаление подписки: DELETE /api/v1/admin/webhooks/{id}.
+func (api *API) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	err := api.hooks.Delete(r.Context(), id)
+	if err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Журнал доставки: GET /api/v1/admin/webhooks/{id}/deliveries?limit=50.
+func (api *API) webhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	limit := defaultDeliveriesLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxDeliveriesLimit {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid limit parameter", nil)
+			return
+		}
+		limit = n
+	}
+	deliveries, err := api.hooks.Deliveries(r.Context(), id, limit)
+	if err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// writeWebhookError сопоставляет ошибку хранилища веб-хуков со статусом ответа.
+func writeWebhookError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, webhooks.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, codeNotFound, err.Error(), nil)
+	case errors.Is(err, webhooks.ErrInvalidArgument):
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error(), nil)
+	default:
+		writeStorageError(w, r, err)
+	}
+}
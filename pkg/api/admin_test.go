@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Skillfactory-APIGateway/pkg/service"
+)
+
+func TestAdminMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		auth   string
+		status int
+	}{
+		{"админ-API отключено", "", "Bearer secret", http.StatusForbidden},
+		{"нет токена", "secret", "", http.StatusUnauthorized},
+		{"неверный токен", "secret", "Bearer wrong", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := New(nil, nil, tt.token)
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/webhooks", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			api.Router().ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("статус = %d, ожидался %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestActorMiddleware(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		auth  string
+		want  string
+	}{
+		{"администратор", "secret", "Bearer secret", "admin"},
+		{"неверный токен", "secret", "Bearer wrong", "ip:192.0.2.1"},
+		{"админ-API отключено", "", "Bearer ", "ip:192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &API{adminToken: tt.token}
+			var got string
+			h := api.actorMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = service.Actor(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/comments/1", nil)
+			req.Header.Set("Authorization", tt.auth)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("автор %q, ожидался %q", got, tt.want)
+			}
+		})
+	}
+}
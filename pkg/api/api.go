@@ -2,33 +2,48 @@
 package api
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
-	"Skillfactory-APIGateway/censorship"
 	dbComments "Skillfactory-APIGateway/comments/storage"
-	"Skillfactory-APIGateway/pkg/storage"
+	"Skillfactory-APIGateway/pkg/feed"
+	"Skillfactory-APIGateway/pkg/gql"
+	"Skillfactory-APIGateway/pkg/service"
+	"Skillfactory-APIGateway/pkg/webhooks"
 
+	"github.com/getkin/kin-openapi/routers"
 	"github.com/gorilla/mux"
 )
 
 type API struct {
-	db         *storage.DB
-	dbComments *dbComments.DB
+	svc        *service.Service
+	hooks      *webhooks.Store
+	adminToken string
 	r          *mux.Router
+	specRouter routers.Router
+	images     *http.Client
 }
 
-// Конструктор API.
-func New(db *storage.DB, dbComments *dbComments.DB) *API {
-	a := API{db: db, dbComments: dbComments, r: mux.NewRouter()}
+// Конструктор API. Административные методы доступны с токеном adminToken,
+// при пустом токене они отключены.
+func New(svc *service.Service, hooks *webhooks.Store, adminToken string) *API {
+	specRouter, err := newSpecRouter()
+	if err != nil {
+		log.Fatal(err)
+	}
+	a := API{svc: svc, hooks: hooks, adminToken: adminToken, r: mux.NewRouter(), specRouter: specRouter,
+		images: newImageClient()}
 	a.r.Use(a.requestIDMiddleware)
 	a.r.Use(a.loggingMiddleware)
+	a.r.Use(a.actorMiddleware)
+	a.r.Use(a.validationMiddleware)
+	a.r.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
 	a.endpoints()
 	return &a
 }
@@ -39,19 +54,85 @@ func (api *API) Router() *mux.Router {
 	return api.r
 }
 
+// Путь административных методов.
+const adminPrefix = "/api/v1/admin/"
+
 // Регистрация методов API в маршрутизаторе запросов.
 func (api *API) endpoints() {
+	// версия API v1
+	v1 := api.r.PathPrefix("/api/v1").Subrouter()
+	// страница новостей с поиском, фильтрами и сортировкой:
+	// /api/v1/news?page=4&limit=20&s=Go&source=1&tag=go&from=2025-01-01&to=2025-01-31&sort=comments&order=desc
+	v1.HandleFunc("/news", api.newsLatestHandler).Methods(http.MethodGet, http.MethodOptions)
+	// поток новых новостей: SSE /api/v1/news/stream?s=Go и WebSocket /api/v1/news/ws?s=Go
+	v1.HandleFunc("/news/stream", api.newsStreamHandler).Methods(http.MethodGet)
+	v1.HandleFunc("/news/ws", api.newsWebSocketHandler).Methods(http.MethodGet)
+	// новость с комментариями: /api/v1/news/1
+	v1.HandleFunc("/news/{id}", api.newsByIDHandler).Methods(http.MethodGet, http.MethodOptions)
+	// комментарии к новости: /api/v1/news/1/comments
+	v1.HandleFunc("/news/{id}/comments", api.newsCommentsHandler).Methods(http.MethodGet, http.MethodOptions)
+	v1.HandleFunc("/news/{id}/comments", api.newsAddCommentHandler).Methods(http.MethodPost)
+	// ленты-источники новостей: /api/v1/sources
+	v1.HandleFunc("/sources", api.sourcesHandler).Methods(http.MethodGet, http.MethodOptions)
+	// теги новостей с числом новостей: /api/v1/tags
+	v1.HandleFunc("/tags", api.tagsHandler).Methods(http.MethodGet, http.MethodOptions)
+	// прокси картинок из текста новостей: /api/v1/image?url=...
+	v1.HandleFunc("/image", api.imageHandler).Methods(http.MethodGet)
+	// удаление комментария: /api/v1/comments/1?reason=spam
+	v1.HandleFunc("/comments/{id}", api.deleteCommentHandler).Methods(http.MethodDelete, http.MethodOptions)
+
+	// административные методы: /api/v1/admin/...
+	// запрос проверяется по спецификации только после проверки токена
+	admin := v1.PathPrefix("/admin").Subrouter()
+	admin.Use(api.adminMiddleware, api.validate)
+	// подписки на веб-хуки
+	admin.HandleFunc("/webhooks", api.createWebhookHandler).Methods(http.MethodPost)
+	admin.HandleFunc("/webhooks", api.webhooksHandler).Methods(http.MethodGet)
+	admin.HandleFunc("/webhooks/{id}", api.deleteWebhookHandler).Methods(http.MethodDelete)
+	admin.HandleFunc("/webhooks/{id}/deliveries", api.webhookDeliveriesHandler).Methods(http.MethodGet)
+	// RSS-ленты, изменения применяются без перезапуска
+	admin.HandleFunc("/feeds", api.createFeedHandler).Methods(http.MethodPost)
+	admin.HandleFunc("/feeds", api.feedsHandler).Methods(http.MethodGet)
+	// импорт и экспорт списка лент в формате OPML
+	admin.HandleFunc("/feeds/import", api.importFeedsHandler).Methods(http.MethodPost)
+	admin.HandleFunc("/feeds/export", api.exportFeedsHandler).Methods(http.MethodGet)
+	admin.HandleFunc("/feeds/{id}", api.feedByIDHandler).Methods(http.MethodGet)
+	admin.HandleFunc("/feeds/{id}", api.updateFeedHandler).Methods(http.MethodPatch)
+	admin.HandleFunc("/feeds/{id}", api.deleteFeedHandler).Methods(http.MethodDelete)
+	admin.HandleFunc("/feeds/{id}/fetch", api.fetchFeedHandler).Methods(http.MethodPost)
+	// показатели стадий конвейера сбора новостей
+	admin.HandleFunc("/ingest", api.ingestMetricsHandler).Methods(http.MethodGet)
+	// журнал добавления, изменения и удаления комментариев
+	admin.HandleFunc("/comments/audit", api.commentAuditHandler).Methods(http.MethodGet)
+	// изменение текста комментария: /api/v1/admin/comments/1
+	admin.HandleFunc("/comments/{id}", api.editCommentHandler).Methods(http.MethodPatch)
+
+	// Устаревшие маршруты, оставлены для совместимости.
 	// получить страницу с определенным номером: http://localhost/news/latest?page=4&s=Go или /news/latest?page=1
-	api.r.HandleFunc("/news/latest", api.newsLatestHandler).Methods(http.MethodGet, http.MethodOptions)
+	api.r.HandleFunc("/news/latest", deprecated("/api/v1/news", api.newsLatestHandler)).Methods(http.MethodGet, http.MethodOptions)
 	// поиск новости с комментарием по id: http://localhost/news/detailed?id=1
-	api.r.HandleFunc("/news/detailed", api.newsDetailedHandler).Methods(http.MethodGet, http.MethodOptions)
+	api.r.HandleFunc("/news/detailed", deprecated("/api/v1/news/{id}", api.newsDetailedHandler)).Methods(http.MethodGet, http.MethodOptions)
+	// поток новых новостей в формате SSE
+	api.r.HandleFunc("/news/stream", api.newsStreamHandler).Methods(http.MethodGet)
 	// получить n последних новостей
-	api.r.HandleFunc("/news/{n}", api.posts).Methods(http.MethodGet, http.MethodOptions)
+	api.r.HandleFunc("/news/{n}", deprecated("/api/v1/news", api.posts)).Methods(http.MethodGet, http.MethodOptions)
 
 	// обработчиков комментариев http://localhost/comments?news_id=1
-	api.r.HandleFunc("/comments/add", api.addCommentHandler).Methods(http.MethodPost, http.MethodOptions)
-	api.r.HandleFunc("/comments/del", api.deletePostHandler).Methods(http.MethodDelete, http.MethodOptions)
-	api.r.HandleFunc("/comments", api.commentsHandler).Methods(http.MethodGet, http.MethodOptions)
+	api.r.HandleFunc("/comments/add", deprecated("/api/v1/news/{id}/comments", api.addCommentHandler)).Methods(http.MethodPost, http.MethodOptions)
+	api.r.HandleFunc("/comments/del", deprecated("/api/v1/comments/{id}", api.deletePostHandler)).Methods(http.MethodDelete, http.MethodOptions)
+	api.r.HandleFunc("/comments", deprecated("/api/v1/news/{id}/comments", api.commentsHandler)).Methods(http.MethodGet, http.MethodOptions)
+
+	// GraphQL: http://localhost/graphql
+	api.r.Handle("/graphql", gql.Handler(api.svc)).Methods(http.MethodPost, http.MethodOptions)
+
+	// ленты новостей для RSS-ридеров: http://localhost/feed.rss?s=Go&source=1
+	api.r.HandleFunc("/feed.rss", api.feedHandler("application/rss+xml; charset=utf-8", feed.RSS)).Methods(http.MethodGet)
+	api.r.HandleFunc("/feed.atom", api.feedHandler("application/atom+xml; charset=utf-8", feed.Atom)).Methods(http.MethodGet)
+	api.r.HandleFunc("/feed.json", api.feedHandler("application/feed+json; charset=utf-8", feed.JSON)).Methods(http.MethodGet)
+
+	// документация API
+	api.r.HandleFunc("/openapi.json", api.openapiHandler).Methods(http.MethodGet, http.MethodOptions)
+	api.r.HandleFunc("/docs", api.docsHandler).Methods(http.MethodGet)
 
 	// все публикации
 	api.r.PathPrefix("/").Handler(http.StripPrefix("/", http.FileServer(http.Dir("./webapp"))))
@@ -66,52 +147,31 @@ func (api *API) posts(w http.ResponseWriter, r *http.Request) {
 	}
 	s := mux.Vars(r)["n"]
 	n, _ := strconv.Atoi(s)
-	news, err := api.db.News(n)
+	news, err := api.svc.News(r.Context(), n)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeStorageError(w, r, err)
 		return
 	}
 	json.NewEncoder(w).Encode(news)
 }
 
-// Получение страницу с определенным номером и поиск
+// Получение страницу с определенным номером, поиск, фильтры и сортировка
 func (api *API) newsLatestHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	pageParam := r.URL.Query().Get("page")
-	page := 1
-	if pageParam != "" {
-		var err error
-		page, err = strconv.Atoi(pageParam)
-		if err != nil {
-			http.Error(w, "Invalid page parameter", http.StatusBadRequest)
-			return
-		}
+	page, limit, sort, ok := newsPage(w, r)
+	if !ok {
+		return
 	}
 
-	searchQuery := r.URL.Query().Get("s")
-
-	var posts []storage.Post
-	var err error
-	var pagination storage.Pagination
-
-	if searchQuery != "" {
-		// Поиск с пагинацией
-		posts, pagination, err = api.db.PostSearchILIKE(searchQuery, 10, (page-1)*10)
-	} else {
-		// Обычный список с пагинацией
-		posts, err = api.db.Posts((page - 1) * 10)
-		// Для простоты считаем что у нас фиксированное количество страниц
-		// В реальной системе нужно делать COUNT запрос
-		pagination = storage.Pagination{
-			Page:       page,
-			Limit:      10,
-			NumOfPages: 10,
-		}
+	f, ok := newsFilter(w, r)
+	if !ok {
+		return
 	}
 
+	posts, pagination, err := api.svc.Latest(r.Context(), f, sort, page, limit)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeStorageError(w, r, err)
 		return
 	}
 
@@ -123,7 +183,7 @@ func (api *API) newsLatestHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// Получение публикаций по id
+// Получение публикаций по id.
 func (api *API) newsDetailedHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -131,27 +191,29 @@ func (api *API) newsDetailedHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := r.URL.Query().Get("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
-		http.Error(w, "Invalid id parameter", http.StatusBadRequest)
-		return
-	}
-
-	// Получаем новость
-	post, err := api.db.PostDetal(id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid id parameter", nil)
 		return
 	}
+	api.newsDetailed(w, r, id)
+}
 
-	// Получаем комментарии
-	comments, err := api.dbComments.AllComments(id)
+// newsDetailed отдаёт новость с комментариями. Если комментарии
+// недоступны, в ответ добавляется признак деградации.
+func (api *API) newsDetailed(w http.ResponseWriter, r *http.Request, id int) {
+	d, err := api.svc.Detailed(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeStorageError(w, r, err)
 		return
 	}
 
 	response := map[string]interface{}{
-		"news":     post,
-		"comments": comments,
+		"news":     d.Post,
+		"comments": d.Comments,
+	}
+	if d.CommentsErr != nil {
+		log.Printf("комментарии к новости %d недоступны: %v", id, d.CommentsErr)
+		response["comments_error"] = "comments are temporarily unavailable"
+		response["degraded"] = true
 	}
 
 	json.NewEncoder(w).Encode(response)
@@ -165,19 +227,20 @@ func (api *API) commentsHandler(w http.ResponseWriter, r *http.Request) {
 	parseId := r.URL.Query().Get("news_id")
 	newsId, err := strconv.Atoi(parseId)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-	comments, err := api.dbComments.AllComments(newsId)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid news_id parameter", nil)
 		return
 	}
-	err = json.NewEncoder(w).Encode(comments)
+	api.comments(w, r, newsId)
+}
+
+// comments отдаёт комментарии к новости.
+func (api *API) comments(w http.ResponseWriter, r *http.Request, newsId int) {
+	comments, err := api.svc.Comments(r.Context(), newsId)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeStorageError(w, r, err)
 		return
 	}
+	json.NewEncoder(w).Encode(comments)
 }
 
 // Добавление комментария
@@ -188,48 +251,35 @@ func (api *API) addCommentHandler(w http.ResponseWriter, r *http.Request) {
 	var c dbComments.Comment
 	err := json.NewDecoder(r.Body).Decode(&c)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	// Проверка цензуры
-	allowed, err := api.checkCensorship(c.Content)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	if !allowed {
-		http.Error(w, "Comment contains forbidden words", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "request body is not valid JSON", nil)
 		return
 	}
+	api.addComment(w, r, c)
+}
 
-	err = api.dbComments.AddComment(c)
+// addComment проверяет комментарий цензурой и сохраняет его.
+func (api *API) addComment(w http.ResponseWriter, r *http.Request, c dbComments.Comment) {
+	c, err := api.svc.AddComment(r.Context(), c)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeCommentError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
 }
 
-func (api *API) checkCensorship(comment string) (bool, error) {
-	reqBody, err := json.Marshal(map[string]string{"comment": comment})
-	if err != nil {
-		return false, err
+// writeCommentError сопоставляет ошибку сохранения комментария
+// со статусом ответа.
+func writeCommentError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrCensorshipUnavailable):
+		log.Printf("request_id: %s: %v", requestID(r), err)
+		writeError(w, r, http.StatusBadGateway, codeUpstreamUnavailable, "censorship service is unavailable", nil)
+	case errors.Is(err, service.ErrForbiddenContent):
+		writeError(w, r, http.StatusUnprocessableEntity, codeForbiddenContent, "comment contains forbidden words", nil)
+	default:
+		writeStorageError(w, r, err)
 	}
-
-	resp, err := http.Post("http://localhost:8082/check", "application/json", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return false, err
-	}
-	defer resp.Body.Close()
-
-	var result censorship.Response
-	err = json.NewDecoder(resp.Body).Decode(&result)
-	if err != nil {
-		return false, err
-	}
-
-	return result.Allowed, nil
 }
 
 // Удаление комента.
@@ -239,12 +289,17 @@ func (api *API) deletePostHandler(w http.ResponseWriter, r *http.Request) {
 	var c dbComments.Comment
 	err := json.NewDecoder(r.Body).Decode(&c)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "request body is not valid JSON", nil)
 		return
 	}
-	err = api.dbComments.DeleteComment(c)
+	api.deleteComment(w, r, c.ID, "")
+}
+
+// deleteComment помечает комментарий удалённым.
+func (api *API) deleteComment(w http.ResponseWriter, r *http.Request, id int, reason string) {
+	err := api.svc.DeleteComment(r.Context(), id, reason)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeStorageError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
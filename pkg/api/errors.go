@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	dbComments "Skillfactory-APIGateway/comments/storage"
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+// Машиночитаемые коды ошибок API.
+const (
+	codeBadRequest          = "bad_request"
+	codeInvalidJSON         = "invalid_json"
+	codeValidationFailed    = "validation_failed"
+	codeUnauthorized        = "unauthorized"
+	codeForbidden           = "forbidden"
+	codeNotFound            = "not_found"
+	codeMethodNotAllowed    = "method_not_allowed"
+	codeConflict            = "conflict"
+	codeTooLarge            = "request_too_large"
+	codeForbiddenContent    = "forbidden_content"
+	codeTimeout             = "timeout"
+	codeUpstreamUnavailable = "upstream_unavailable"
+	codeInternal            = "internal_error"
+)
+
+// Error - ошибка API, которая отдаётся клиенту в формате JSON.
+type Error struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	RequestID string      `json:"request_id,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+// Конверт ответа с ошибкой.
+type errorResponse struct {
+	Error Error `json:"error"`
+}
+
+// writeError отправляет клиенту ошибку в едином формате.
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string, details interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: Error{
+		Code:      code,
+		Message:   message,
+		RequestID: requestID(r),
+		Details:   details,
+	}})
+}
+
+// writeStorageError сопоставляет ошибку хранилища со статусом ответа.
+// Текст внутренних ошибок в ответ не попадает, только в журнал.
+func writeStorageError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrSourceNotFound), errors.Is(err, dbComments.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, codeNotFound, err.Error(), nil)
+	case errors.Is(err, storage.ErrInvalidArgument), errors.Is(err, dbComments.ErrInvalidArgument):
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error(), nil)
+	case errors.Is(err, storage.ErrConflict):
+		writeError(w, r, http.StatusConflict, codeConflict, err.Error(), nil)
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, r, http.StatusGatewayTimeout, codeTimeout, "storage did not respond in time", nil)
+	default:
+		log.Printf("ошибка хранилища, request_id: %s: %v", requestID(r), err)
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error", nil)
+	}
+}
+
+// Ответ на запрос с неподдерживаемым методом.
+func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed, "method not allowed", nil)
+}
+
+// requestID возвращает идентификатор запроса из контекста.
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value("request_id").(string)
+	return id
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dbComments "Skillfactory-APIGateway/comments/storage"
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+func TestWriteStorageError(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{storage.ErrNotFound, http.StatusNotFound, codeNotFound},
+		{dbComments.ErrNotFound, http.StatusNotFound, codeNotFound},
+		{fmt.Errorf("%w: id", storage.ErrInvalidArgument), http.StatusBadRequest, codeBadRequest},
+		{fmt.Errorf("%w: text", dbComments.ErrInvalidArgument), http.StatusBadRequest, codeBadRequest},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout, codeTimeout},
+		{errors.New("pq: connection refused"), http.StatusInternalServerError, codeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req = req.WithContext(context.WithValue(req.Context(), "request_id", "42"))
+			rec := httptest.NewRecorder()
+			writeStorageError(rec, req, tt.err)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("код ответа = %d, ожидался %d", rec.Code, tt.wantStatus)
+			}
+			var resp errorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error.Code != tt.wantCode {
+				t.Errorf("код ошибки = %q, ожидался %q", resp.Error.Code, tt.wantCode)
+			}
+			if resp.Error.RequestID != "42" {
+				t.Errorf("request_id = %q, ожидался 42", resp.Error.RequestID)
+			}
+			if tt.wantCode == codeInternal && resp.Error.Message == tt.err.Error() {
+				t.Error("текст внутренней ошибки не должен попадать клиенту")
+			}
+		})
+	}
+}
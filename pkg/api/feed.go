@@ -0,0 +1,83 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"Skillfactory-APIGateway/pkg/feed"
+)
+
+// Время, на которое клиенты и прокси могут сохранить ленту.
+const feedMaxAge = "public, max-age=300"
+
+// Лента новостей: /feed.rss, /feed.atom, /feed.json?s=Go&source=1.
+// Поиск и источник задаются так же, как в списке новостей, чтобы
+// на результат поиска можно было подписаться в любом RSS-ридере.
+func (api *API) feedHandler(contentType string, write func(io.Writer, feed.Feed) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f, ok := newsFilter(w, r)
+		if !ok {
+			return
+		}
+		posts, err := api.svc.Feed(r.Context(), f)
+		if err != nil {
+			writeStorageError(w, r, err)
+			return
+		}
+
+		title := "GoNews"
+		if f.Search != "" {
+			title += ": " + f.Search
+		}
+		if f.Tag != "" {
+			title += " #" + f.Tag
+		}
+		if f.SourceID != 0 && len(posts) > 0 {
+			title += " (" + posts[0].SourceName + ")"
+		}
+		var buf bytes.Buffer
+		err = write(&buf, feed.Feed{
+			Title:       title,
+			Description: "Агрегатор новостей GoNews",
+			Link:        baseURL(r) + "/",
+			FeedURL:     baseURL(r) + r.URL.RequestURI(),
+			Items:       posts,
+		})
+		if err != nil {
+			writeStorageError(w, r, err)
+			return
+		}
+		serveFeed(w, r, contentType, buf.Bytes(), feed.Feed{Items: posts}.Updated())
+	}
+}
+
+// serveFeed отдаёт ленту с заголовками кэширования. На условные
+// запросы с совпадающим ETag или Last-Modified отвечает 304.
+func serveFeed(w http.ResponseWriter, r *http.Request, contentType string, body []byte, modified time.Time) {
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", feedMaxAge)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	if modified.Unix() <= 0 {
+		modified = time.Time{}
+	}
+	http.ServeContent(w, r, "", modified, bytes.NewReader(body))
+}
+
+// baseURL возвращает схему и хост, по которым клиент обратился к серверу.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if p := r.Header.Get("X-Forwarded-Proto"); p != "" {
+		scheme = strings.ToLower(p)
+	}
+	return scheme + "://" + r.Host
+}
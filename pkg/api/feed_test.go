@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServeFeedConditional(t *testing.T) {
+	body := []byte("<rss/>")
+	modified := time.Unix(1718000000, 0)
+
+	rec := httptest.NewRecorder()
+	serveFeed(rec, httptest.NewRequest(http.MethodGet, "/feed.rss", nil), "application/rss+xml", body, modified)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус = %d", rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" || rec.Header().Get("Cache-Control") != feedMaxAge {
+		t.Fatalf("нет заголовков кэширования: %v", rec.Header())
+	}
+	if rec.Header().Get("Last-Modified") != "Mon, 10 Jun 2024 06:13:20 GMT" {
+		t.Errorf("Last-Modified = %q", rec.Header().Get("Last-Modified"))
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+	}{
+		{"If-None-Match", "If-None-Match", etag},
+		{"If-Modified-Since", "If-Modified-Since", "Mon, 10 Jun 2024 06:13:20 GMT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/feed.rss", nil)
+			req.Header.Set(tt.header, tt.value)
+			rec := httptest.NewRecorder()
+			serveFeed(rec, req, "application/rss+xml", body, modified)
+			if rec.Code != http.StatusNotModified {
+				t.Fatalf("статус = %d, ожидался 304", rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Наибольший размер картинки, которую отдаёт прокси.
+const maxImageSize = 10 << 20
+
+// Время, на которое клиенты и прокси могут сохранить картинку.
+const imageMaxAge = "public, max-age=86400"
+
+var errPrivateAddress = errors.New("адрес во внутренней сети")
+
+// newImageClient создаёт клиент прокси картинок. Соединения с адресами
+// внутренних сетей запрещены, чтобы через прокси нельзя было обратиться
+// к внутренним сервисам.
+func newImageClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: publicOnly}
+	return &http.Client{
+		Timeout: 20 * time.Second,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: 10 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("слишком много перенаправлений")
+			}
+			return nil
+		},
+	}
+}
+
+// publicOnly запрещает соединения с внутренними адресами. Проверяется
+// адрес после разрешения имени, поэтому подмена DNS не помогает.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// Картинка из текста новости: /api/v1/image?url=https%3A%2F%2Fhabr.com%2Fimg.png.
+// Очищенный HTML новостей ссылается на картинки через прокси, поэтому
+// сайты-источники не видят адресов читателей, а картинки с http-сайтов
+// показываются на https-странице.
+func (api *API) imageHandler(w http.ResponseWriter, r *http.Request) {
+	u, err := url.Parse(r.URL.Query().Get("url"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "url must be an absolute http(s) address", nil)
+		return
+	}
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, u.String(), nil)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "url must be an absolute http(s) address", nil)
+		return
+	}
+	req.Header.Set("User-Agent", "GoNews-ImageProxy/1.0")
+	req.Header.Set("Accept", "image/*")
+	resp, err := api.images.Do(req)
+	if err != nil {
+		writeError(w, r, http.StatusBadGateway, codeUpstreamUnavailable, "image is unavailable", nil)
+		return
+	}
+	defer resp.Body.Close()
+
+	// SVG может содержать скрипты, поэтому не отдаётся
+	contentType := resp.Header.Get("Content-Type")
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(contentType, "image/") ||
+		strings.HasPrefix(contentType, "image/svg") || resp.ContentLength > maxImageSize {
+		writeError(w, r, http.StatusBadGateway, codeUpstreamUnavailable, "image is unavailable", nil)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", imageMaxAge)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	if resp.ContentLength > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+	}
+	io.Copy(w, io.LimitReader(resp.Body, maxImageSize))
+}
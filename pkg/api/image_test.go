@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestImageHandler(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/img.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("png"))
+		case "/img.svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write([]byte("<svg/>"))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html>"))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		url    string
+		public bool // разрешить тестовому серверу на 127.0.0.1
+		status int
+	}{
+		{"картинка", srv.URL + "/img.png", true, http.StatusOK},
+		{"svg", srv.URL + "/img.svg", true, http.StatusBadGateway},
+		{"не картинка", srv.URL + "/page", true, http.StatusBadGateway},
+		{"внутренний адрес", srv.URL + "/img.png", false, http.StatusBadGateway},
+		{"не http", "file:///etc/passwd", true, http.StatusBadRequest},
+		{"пустой адрес", "", true, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := New(nil, nil, "")
+			if tt.public {
+				api.images = srv.Client()
+			}
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/image?url="+url.QueryEscape(tt.url), nil)
+			rec := httptest.NewRecorder()
+			api.Router().ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("статус = %d, ожидался %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status == http.StatusOK {
+				if rec.Body.String() != "png" || rec.Header().Get("Content-Type") != "image/png" ||
+					rec.Header().Get("Cache-Control") != imageMaxAge {
+					t.Errorf("ответ %q, заголовки %v", rec.Body, rec.Header())
+				}
+			}
+		})
+	}
+}
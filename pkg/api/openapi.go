@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// Спецификация OpenAPI всех маршрутов, зарегистрированных в endpoints().
+//
+//go:embed openapi.json
+var openapiSpec []byte
+
+// Страница Swagger UI, которая загружает спецификацию с /openapi.json.
+const swaggerUI = `<!DOCTYPE html>
+<html lang="ru">
+<head>
+  <meta charset="utf-8">
+  <title>GoNews API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// Тела запросов в формате XML (импорт OPML) проверяются только
+// на наличие, содержимое разбирает обработчик.
+func init() {
+	openapi3filter.RegisterBodyDecoder("text/x-opml", openapi3filter.FileBodyDecoder)
+	openapi3filter.RegisterBodyDecoder("application/xml", openapi3filter.FileBodyDecoder)
+}
+
+// loadSpec разбирает и проверяет встроенную спецификацию.
+func loadSpec() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(openapiSpec)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось разобрать спецификацию OpenAPI: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("некорректная спецификация OpenAPI: %v", err)
+	}
+	return doc, nil
+}
+
+// Выдача спецификации OpenAPI.
+func (api *API) openapiHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(openapiSpec)
+}
+
+// Страница Swagger UI.
+func (api *API) docsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUI))
+}
+
+// Наибольший размер тела запроса, которое читается при проверке.
+const maxRequestBody = 1 << 20
+
+// Middleware для проверки параметров и тела запроса по спецификации.
+// Запросы к маршрутам, которых нет в спецификации, пропускаются без проверки.
+// Административные запросы проверяются только после проверки токена,
+// в подмаршрутизаторе admin (см. endpoints).
+func (api *API) validationMiddleware(next http.Handler) http.Handler {
+	validated := api.validate(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, adminPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		validated.ServeHTTP(w, r)
+	})
+}
+
+// validate проверяет запрос по спецификации. Тело читается
+// не больше maxRequestBody байт.
+func (api *API) validate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := api.specRouter.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+		}
+		err = openapi3filter.ValidateRequest(r.Context(), input)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, codeTooLarge, "request body is too large", nil)
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, codeValidationFailed, "request does not match the API specification", validationDetails(err))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validationDetails описывает, какая часть запроса не прошла проверку.
+func validationDetails(err error) map[string]string {
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		reason := reqErr.Reason
+		if reqErr.Err != nil {
+			reason = reqErr.Err.Error()
+		}
+		if reqErr.Parameter != nil {
+			return map[string]string{
+				"in":     reqErr.Parameter.In,
+				"name":   reqErr.Parameter.Name,
+				"reason": reason,
+			}
+		}
+		if reqErr.RequestBody != nil {
+			return map[string]string{
+				"in":     "body",
+				"reason": reason,
+			}
+		}
+	}
+	return map[string]string{"reason": err.Error()}
+}
+
+// newSpecRouter строит маршрутизатор по спецификации для поиска
+// описания операции, соответствующей запросу.
+func newSpecRouter() (routers.Router, error) {
+	doc, err := loadSpec()
+	if err != nil {
+		return nil, err
+	}
+	return gorillamux.NewRouter(doc)
+}
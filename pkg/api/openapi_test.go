@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// Маршруты API и спецификация должны описывать один и тот же набор операций.
+func TestSpecMatchesRouter(t *testing.T) {
+	doc, err := loadSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := New(nil, nil, "")
+
+	routed := map[string]bool{}
+	err = api.Router().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// маршрут без методов - раздача статики веб-приложения
+			return nil
+		}
+		for _, m := range methods {
+			if m == http.MethodOptions {
+				continue
+			}
+			routed[m+" "+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	specified := map[string]bool{}
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			specified[method+" "+path] = true
+		}
+	}
+
+	var missing, extra []string
+	for op := range routed {
+		if !specified[op] {
+			missing = append(missing, op)
+		}
+	}
+	for op := range specified {
+		if !routed[op] {
+			extra = append(extra, op)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	if len(missing) > 0 {
+		t.Errorf("маршруты не описаны в спецификации: %v", missing)
+	}
+	if len(extra) > 0 {
+		t.Errorf("операции спецификации не зарегистрированы в маршрутизаторе: %v", extra)
+	}
+}
+
+func TestValidationMiddleware(t *testing.T) {
+	api := New(nil, nil, "")
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{"нечисловой id", http.MethodGet, "/news/detailed?id=abc", "", http.StatusBadRequest},
+		{"нет id", http.MethodGet, "/news/detailed", "", http.StatusBadRequest},
+		{"страница меньше 1", http.MethodGet, "/news/latest?page=0", "", http.StatusBadRequest},
+		{"нет news_id", http.MethodGet, "/comments", "", http.StatusBadRequest},
+		{"пустой комментарий", http.MethodPost, "/comments/add", `{"newsID":1,"content":""}`, http.StatusBadRequest},
+		{"тело не JSON", http.MethodDelete, "/comments/del", `id=1`, http.StatusBadRequest},
+		{"v1: нечисловой id", http.MethodGet, "/api/v1/news/abc", "", http.StatusBadRequest},
+		{"v1: страница меньше 1", http.MethodGet, "/api/v1/news?page=0", "", http.StatusBadRequest},
+		{"v1: пустой комментарий", http.MethodPost, "/api/v1/news/1/comments", `{"content":""}`, http.StatusBadRequest},
+		{"v1: id комментария 0", http.MethodDelete, "/api/v1/comments/0", "", http.StatusBadRequest},
+		{"спецификация", http.MethodGet, "/openapi.json", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			api.Router().ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("код ответа = %d, ожидался %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			if rec.Code == http.StatusBadRequest {
+				var resp errorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Error.Code != codeValidationFailed {
+					t.Errorf("код ошибки = %q, ожидался %q", resp.Error.Code, codeValidationFailed)
+				}
+			}
+		})
+	}
+}
+
+func TestAdminValidation(t *testing.T) {
+	api := New(nil, nil, "secret")
+	huge := strings.Repeat("x", maxRequestBody+1)
+	tests := []struct {
+		name        string
+		token       string
+		target      string
+		contentType string
+		body        string
+		want        int
+	}{
+		// токен проверяется до чтения тела
+		{"без токена", "", "/api/v1/admin/feeds/import", "text/x-opml", huge, http.StatusUnauthorized},
+		{"без токена, тело не JSON", "", "/api/v1/admin/webhooks", "application/json", "x", http.StatusUnauthorized},
+		{"большое тело", "secret", "/api/v1/admin/feeds/import", "text/x-opml", huge, http.StatusRequestEntityTooLarge},
+		{"тело не JSON", "secret", "/api/v1/admin/webhooks", "application/json", "x", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			api.Router().ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("код ответа = %d, ожидался %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"Skillfactory-APIGateway/pkg/opml"
+	"Skillfactory-APIGateway/pkg/service"
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+// Максимальный размер загружаемого OPML.
+const maxOPMLSize = 1 << 20
+
+// Изменяемые поля ленты. Поля, которых нет в запросе, не меняются.
+type sourcePatch struct {
+	Name     *string            `json:"name"`
+	URL      *string            `json:"url"`
+	Category *string            `json:"category"`
+	Interval *int               `json:"interval"`
+	Enabled  *bool              `json:"enabled"`
+	TagRules *[]storage.TagRule `json:"tag_rules"`
+}
+
+// apply переносит заданные поля в ленту.
+func (p sourcePatch) apply(src *storage.Source) {
+	if p.Name != nil {
+		src.Name = *p.Name
+	}
+	if p.URL != nil {
+		src.URL = *p.URL
+	}
+	if p.Category != nil {
+		src.Category = *p.Category
+	}
+	if p.Interval != nil {
+		src.Interval = *p.Interval
+	}
+	if p.Enabled != nil {
+		src.Enabled = *p.Enabled
+	}
+	if p.TagRules != nil {
+		src.TagRules = *p.TagRules
+	}
+}
+
+// Список лент: GET /api/v1/admin/feeds.
+func (api *API) feedsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	sources, err := api.svc.Sources(r.Context())
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(sources)
+}
+
+// Добавление ленты: POST /api/v1/admin/feeds. По умолчанию лента
+// включена и опрашивается раз в storage.DefaultInterval минут.
+func (api *API) createFeedHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var p sourcePatch
+	err := json.NewDecoder(r.Body).Decode(&p)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "request body is not valid JSON", nil)
+		return
+	}
+	src := storage.Source{Interval: storage.DefaultInterval, Enabled: true}
+	p.apply(&src)
+	src, err = api.svc.CreateSource(r.Context(), src)
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(src)
+}
+
+// Лента по id: GET /api/v1/admin/feeds/{id}.
+func (api *API) feedByIDHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	src, err := api.svc.Source(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(src)
+}
+
+// Изменение ленты: PATCH /api/v1/admin/feeds/{id}. Новые адрес
+// и интервал сразу применяются к опросу ленты.
+func (api *API) updateFeedHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var p sourcePatch
+	err := json.NewDecoder(r.Body).Decode(&p)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "request body is not valid JSON", nil)
+		return
+	}
+	src, err := api.svc.Source(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+	p.apply(&src)
+	src, err = api.svc.UpdateSource(r.Context(), src)
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(src)
+}
+
+// Удаление ленты: DELETE /api/v1/admin/feeds/{id}.
+func (api *API) deleteFeedHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	err := api.svc.DeleteSource(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Внеочередной опрос ленты: POST /api/v1/admin/feeds/{id}/fetch.
+// Опрос выполняется в фоне, ответ не ждёт его завершения.
+func (api *API) fetchFeedHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	err := api.svc.FetchSource(r.Context(), id)
+	if errors.Is(err, service.ErrSourceDisabled) {
+		writeError(w, r, http.StatusConflict, codeConflict, "feed is disabled", nil)
+		return
+	}
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Показатели конвейера сбора новостей: GET /api/v1/admin/ingest.
+func (api *API) ingestMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.svc.IngestMetrics())
+}
+
+// Импорт лент из OPML: POST /api/v1/admin/feeds/import. Файл передаётся
+// телом запроса или полем file формы multipart/form-data. Папки OPML
+// становятся категориями лент, уже известные ленты пропускаются.
+func (api *API) importFeedsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	r.Body = http.MaxBytesReader(w, r.Body, maxOPMLSize)
+	var body io.Reader = r.Body
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" {
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "form field file is required", nil)
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+	sources, err := opml.Parse(body)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error(), nil)
+		return
+	}
+	res, err := api.svc.ImportSources(r.Context(), sources)
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+// Экспорт лент в OPML: GET /api/v1/admin/feeds/export.
+func (api *API) exportFeedsHandler(w http.ResponseWriter, r *http.Request) {
+	sources, err := api.svc.Sources(r.Context())
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="gonews.opml"`)
+	opml.Write(w, "GoNews", sources)
+}
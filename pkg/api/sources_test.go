@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+func TestSourcePatch(t *testing.T) {
+	src := storage.Source{ID: 1, Name: "Habr", URL: "https://habr.com/rss", Interval: 25, Enabled: true}
+	var p sourcePatch
+	err := json.Unmarshal([]byte(`{"interval": 5, "enabled": false, "tag_rules": [{"tag": "go", "keywords": ["golang"]}]}`), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.apply(&src)
+	want := storage.Source{ID: 1, Name: "Habr", URL: "https://habr.com/rss", Interval: 5, Enabled: false,
+		TagRules: []storage.TagRule{{Tag: "go", Keywords: []string{"golang"}}}}
+	if !reflect.DeepEqual(src, want) {
+		t.Errorf("после изменения %+v, ожидалось %+v", src, want)
+	}
+}
+
+func TestImportFeedsInvalidOPML(t *testing.T) {
+	for _, ct := range []string{"text/x-opml", "application/xml"} {
+		t.Run(ct, func(t *testing.T) {
+			api := New(nil, nil, "secret")
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/feeds/import", strings.NewReader("<html>"))
+			req.Header.Set("Authorization", "Bearer secret")
+			req.Header.Set("Content-Type", ct)
+			rec := httptest.NewRecorder()
+			api.Router().ServeHTTP(rec, req)
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), codeBadRequest) {
+				t.Fatalf("ответ %d %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"Skillfactory-APIGateway/pkg/events"
+	"Skillfactory-APIGateway/pkg/storage"
+
+	"github.com/gorilla/websocket"
+)
+
+// Интервал служебных сообщений, которые не дают прокси закрыть
+// простаивающее соединение.
+const streamHeartbeat = 15 * time.Second
+
+// Сообщение потока новостей.
+type streamMessage struct {
+	ID   uint64       `json:"id"`
+	Type string       `json:"type"`
+	Post storage.Post `json:"post"`
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// API и так открыт для любых источников (Access-Control-Allow-Origin: *)
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// Поток новых новостей в формате Server-Sent Events:
+// /api/v1/news/stream?s=Go. Номер последнего полученного события
+// передаётся в заголовке Last-Event-ID или параметре last_event_id.
+func (api *API) newsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "streaming is not supported", nil)
+		return
+	}
+	lastID, ok := lastEventID(w, r)
+	if !ok {
+		return
+	}
+	search := r.URL.Query().Get("s")
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	sub := api.svc.Subscribe(lastID)
+	defer sub.Close()
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if e.Type != events.PostCreated || !events.MatchTitle(e.Post, search) {
+				continue
+			}
+			b, err := json.Marshal(e.Post)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, b)
+			flusher.Flush()
+		}
+	}
+}
+
+// Поток новых новостей через WebSocket: /api/v1/news/ws?s=Go&last_event_id=10.
+// Каждое сообщение - JSON с номером события, типом и новостью.
+func (api *API) newsWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	lastID, ok := lastEventID(w, r)
+	if !ok {
+		return
+	}
+	search := r.URL.Query().Get("s")
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade уже отправил ответ с ошибкой
+		return
+	}
+	defer conn.Close()
+
+	sub := api.svc.Subscribe(lastID)
+	defer sub.Close()
+
+	// Сообщения от клиента не ожидаются, чтение нужно,
+	// чтобы узнать о закрытии соединения.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-heartbeat.C:
+			deadline := time.Now().Add(streamHeartbeat)
+			if err := conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				return
+			}
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if e.Type != events.PostCreated || !events.MatchTitle(e.Post, search) {
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamHeartbeat))
+			if err := conn.WriteJSON(streamMessage{ID: e.ID, Type: e.Type, Post: e.Post}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// lastEventID читает номер последнего полученного клиентом события.
+// При ошибке отправляет ответ клиенту и возвращает false.
+func lastEventID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	s := r.Header.Get("Last-Event-ID")
+	if s == "" {
+		s = r.URL.Query().Get("last_event_id")
+	}
+	if s == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid last event id", nil)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"Skillfactory-APIGateway/pkg/service"
+	"Skillfactory-APIGateway/pkg/storage"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestNewsStream_SSE(t *testing.T) {
+	svc := service.New(nil, nil)
+	srv := httptest.NewServer(New(svc, nil, "").Router())
+	defer srv.Close()
+
+	// событие 1 уже прошло, клиент возобновляет поток после него
+	svc.Publish([]storage.Post{{ID: 10, Title: "Go news"}, {ID: 11, Title: "Rust news"}, {ID: 12, Title: "More Go"}})
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/news/stream?s=go", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	sc := bufio.NewScanner(resp.Body)
+	var lines []string
+	for sc.Scan() && len(lines) < 3 {
+		if sc.Text() != "" {
+			lines = append(lines, sc.Text())
+		}
+	}
+	want := []string{"id: 3", "event: post.created"}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Fatalf("получено %q, ожидалось %q", lines, want)
+		}
+	}
+	if !strings.Contains(lines[2], `"Title":"More Go"`) {
+		t.Errorf("данные события: %s", lines[2])
+	}
+}
+
+func TestNewsStream_WebSocket(t *testing.T) {
+	svc := service.New(nil, nil)
+	srv := httptest.NewServer(New(svc, nil, "").Router())
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/news/ws?s=go"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// подписка на сервере появляется не сразу, поэтому публикуем,
+	// пока клиент не получит новость
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				svc.Publish([]storage.Post{{ID: 1, Title: "Rust"}, {ID: 2, Title: "Go"}})
+			}
+		}
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg streamMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Post.ID != 2 || msg.Type != "post.created" {
+		t.Errorf("получено сообщение %+v, ожидалась новость 2", msg)
+	}
+}
+
+func TestNewsStream_BadLastEventID(t *testing.T) {
+	api := New(service.New(nil, nil), nil, "")
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/news/stream", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	rec := httptest.NewRecorder()
+	api.Router().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("код ответа = %d, ожидался 400", rec.Code)
+	}
+}
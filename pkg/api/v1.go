@@ -0,0 +1,252 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	dbComments "Skillfactory-APIGateway/comments/storage"
+	"Skillfactory-APIGateway/pkg/service"
+	"Skillfactory-APIGateway/pkg/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// Новость с комментариями: GET /api/v1/news/{id}.
+func (api *API) newsByIDHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	api.newsDetailed(w, r, id)
+}
+
+// Комментарии к новости: GET /api/v1/news/{id}/comments.
+func (api *API) newsCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	api.comments(w, r, id)
+}
+
+// Добавление комментария к новости: POST /api/v1/news/{id}/comments.
+func (api *API) newsAddCommentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var c dbComments.Comment
+	err := json.NewDecoder(r.Body).Decode(&c)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "request body is not valid JSON", nil)
+		return
+	}
+	c.NewsID = id
+	api.addComment(w, r, c)
+}
+
+// Удаление комментария: DELETE /api/v1/comments/{id}?reason=...
+// Комментарий остаётся в обсуждении с текстом "[removed]".
+func (api *API) deleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	api.deleteComment(w, r, id, r.URL.Query().Get("reason"))
+}
+
+// Изменение текста комментария администратором: PATCH /api/v1/admin/comments/{id}.
+func (api *API) editCommentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var c dbComments.Comment
+	err := json.NewDecoder(r.Body).Decode(&c)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "request body is not valid JSON", nil)
+		return
+	}
+	c.ID = id
+	c, err = api.svc.EditComment(r.Context(), c)
+	if err != nil {
+		writeCommentError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(c)
+}
+
+// Ленты-источники новостей: GET /api/v1/sources.
+func (api *API) sourcesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		return
+	}
+	sources, err := api.svc.Sources(r.Context())
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(sources)
+}
+
+// Теги новостей с числом новостей: GET /api/v1/tags.
+func (api *API) tagsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		return
+	}
+	tags, err := api.svc.Tags(r.Context())
+	if err != nil {
+		writeStorageError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(tags)
+}
+
+// newsFilter читает фильтр новостей из параметров запроса:
+// s - подстрока заголовка, source - id ленты-источника, tag - тег,
+// from и to - границы времени публикации.
+// При ошибке отправляет ответ клиенту и возвращает false.
+func newsFilter(w http.ResponseWriter, r *http.Request) (storage.Filter, bool) {
+	f := storage.Filter{
+		Search: r.URL.Query().Get("s"),
+		Tag:    storage.NormalizeTag(r.URL.Query().Get("tag")),
+	}
+	if s := r.URL.Query().Get("source"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil || id < 1 {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid source parameter", nil)
+			return storage.Filter{}, false
+		}
+		f.SourceID = id
+	}
+	for _, p := range []struct {
+		name     string
+		endOfDay bool
+		dst      *int64
+	}{{"from", false, &f.From}, {"to", true, &f.To}} {
+		s := r.URL.Query().Get(p.name)
+		if s == "" {
+			continue
+		}
+		t, err := parseTime(s, p.endOfDay)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid "+p.name+" parameter", nil)
+			return storage.Filter{}, false
+		}
+		*p.dst = t
+	}
+	if f.From != 0 && f.To != 0 && f.From > f.To {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "from must not be after to", nil)
+		return storage.Filter{}, false
+	}
+	return f, true
+}
+
+// parseTime читает время в формате RFC 3339, дату 2006-01-02
+// или секунды Unix. Дата без времени означает начало дня по UTC,
+// а при endOfDay - его последнюю секунду. Время публикации хранится
+// в БД как int4, поэтому время вне 1970-01-01..2038-01-19 - ошибка.
+func parseTime(s string, endOfDay bool) (int64, error) {
+	var n int64
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		n = t.Unix()
+	} else if t, err := time.Parse("2006-01-02", s); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		n = t.Unix()
+	} else if n, err = strconv.ParseInt(s, 10, 64); err != nil {
+		return 0, fmt.Errorf("неверное время %q", s)
+	}
+	if n < 1 || n > math.MaxInt32 {
+		return 0, fmt.Errorf("время %q вне допустимого диапазона", s)
+	}
+	return n, nil
+}
+
+// Допустимые значения параметра sort.
+var sorts = map[string]bool{
+	storage.SortPubTime:   true,
+	storage.SortRelevance: true,
+	storage.SortComments:  true,
+}
+
+// newsPage читает из параметров запроса номер страницы page,
+// её размер limit и порядок новостей: sort - pub_time, relevance
+// или comments, order - asc или desc (по умолчанию).
+// При ошибке отправляет ответ клиенту и возвращает false.
+func newsPage(w http.ResponseWriter, r *http.Request) (page, limit int, sort storage.Sort, ok bool) {
+	q := r.URL.Query()
+	for _, p := range []struct {
+		name string
+		max  int
+		dst  *int
+	}{{"page", 0, &page}, {"limit", service.MaxPageSize, &limit}} {
+		s := q.Get(p.name)
+		if s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || (p.max > 0 && n > p.max) {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid "+p.name+" parameter", nil)
+			return 0, 0, storage.Sort{}, false
+		}
+		*p.dst = n
+	}
+	sort.By = q.Get("sort")
+	if sort.By != "" && !sorts[sort.By] {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid sort parameter", nil)
+		return 0, 0, storage.Sort{}, false
+	}
+	switch q.Get("order") {
+	case "", "desc":
+	case "asc":
+		sort.Asc = true
+	default:
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid order parameter", nil)
+		return 0, 0, storage.Sort{}, false
+	}
+	return page, limit, sort, true
+}
+
+// pathID читает идентификатор ресурса из пути запроса.
+// При ошибке отправляет ответ клиенту и возвращает false.
+func pathID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id < 1 {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid id in path", nil)
+		return 0, false
+	}
+	return id, true
+}
+
+// deprecated помечает устаревший маршрут заголовками Deprecation и Link
+// со ссылкой на маршрут, который следует использовать вместо него.
+func deprecated(successor string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		h(w, r)
+	}
+}
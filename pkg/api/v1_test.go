@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	dbComments "Skillfactory-APIGateway/comments/storage"
+	"Skillfactory-APIGateway/pkg/service"
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+func TestDeprecatedRoutes(t *testing.T) {
+	api := New(nil, nil, "")
+	req := httptest.NewRequest(http.MethodOptions, "/news/5", nil)
+	rec := httptest.NewRecorder()
+	api.Router().ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("Deprecation = %q, ожидалось true", got)
+	}
+	if got := rec.Header().Get("Link"); got != `</api/v1/news>; rel="successor-version"` {
+		t.Errorf("Link = %q", got)
+	}
+}
+
+func TestNewsPage(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantPage  int
+		wantLimit int
+		wantSort  storage.Sort
+		wantOK    bool
+	}{
+		{"", 0, 0, storage.Sort{}, true},
+		{"page=3&limit=50&sort=comments&order=asc", 3, 50, storage.Sort{By: storage.SortComments, Asc: true}, true},
+		{"sort=relevance&order=desc", 0, 0, storage.Sort{By: storage.SortRelevance}, true},
+		{"page=0", 0, 0, storage.Sort{}, false},
+		{"limit=101", 0, 0, storage.Sort{}, false},
+		{"limit=abc", 0, 0, storage.Sort{}, false},
+		{"sort=title", 0, 0, storage.Sort{}, false},
+		{"order=up", 0, 0, storage.Sort{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			page, limit, sort, ok := newsPage(rec, httptest.NewRequest(http.MethodGet, "/api/v1/news?"+tt.query, nil))
+			if ok != tt.wantOK || page != tt.wantPage || limit != tt.wantLimit || sort != tt.wantSort {
+				t.Fatalf("newsPage() = %d, %d, %+v, %v; ожидалось %d, %d, %+v, %v",
+					page, limit, sort, ok, tt.wantPage, tt.wantLimit, tt.wantSort, tt.wantOK)
+			}
+			if !ok && rec.Code != http.StatusBadRequest {
+				t.Errorf("статус = %d, ожидался 400", rec.Code)
+			}
+		})
+	}
+}
+
+func TestNewsFilter(t *testing.T) {
+	tests := []struct {
+		query  string
+		want   storage.Filter
+		wantOK bool
+	}{
+		{"", storage.Filter{}, true},
+		{"s=Go&source=2", storage.Filter{Search: "Go", SourceID: 2}, true},
+		{"tag=Open+Source", storage.Filter{Tag: "open source"}, true},
+		{"source=habr", storage.Filter{}, false},
+		{"source=0", storage.Filter{}, false},
+		{"from=2025-01-01&to=2025-01-31", storage.Filter{From: 1735689600, To: 1738367999}, true},
+		{"from=2025-01-01T03:00:00%2B03:00&to=1738367999", storage.Filter{From: 1735689600, To: 1738367999}, true},
+		{"from=вчера", storage.Filter{}, false},
+		{"from=2025-02-01&to=2025-01-01", storage.Filter{}, false},
+		// время публикации в БД - int4
+		{"to=2100-01-01", storage.Filter{}, false},
+		{"from=0001-01-01T00:00:00Z", storage.Filter{}, false},
+		{"from=1969-12-31", storage.Filter{}, false},
+		{"to=4294967296", storage.Filter{}, false},
+		{"to=2038-01-19T03:14:07Z", storage.Filter{To: 2147483647}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			got, ok := newsFilter(rec, httptest.NewRequest(http.MethodGet, "/api/v1/news?"+tt.query, nil))
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("newsFilter() = %+v, %v; ожидалось %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+			if !ok && rec.Code != http.StatusBadRequest {
+				t.Errorf("статус = %d, ожидался 400", rec.Code)
+			}
+		})
+	}
+}
+
+// Хранилище новостей, в котором меняется только число комментариев.
+type countingNews struct {
+	service.NewsStore
+}
+
+func (countingNews) AddCommentCount(ctx context.Context, newsID, delta int) error { return nil }
+
+// Хранилище комментариев в памяти с журналом удалений.
+type memComments struct {
+	service.CommentStore
+	comments []dbComments.Comment
+	audit    []dbComments.AuditEntry
+}
+
+func (m *memComments) AllComments(ctx context.Context, newsID int) ([]dbComments.Comment, error) {
+	var res []dbComments.Comment
+	for _, c := range m.comments {
+		if c.NewsID == newsID {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+func (m *memComments) DeleteComment(ctx context.Context, c dbComments.Comment, actor, reason string) (int, error) {
+	for i := range m.comments {
+		if m.comments[i].ID == c.ID && !m.comments[i].Deleted {
+			m.comments[i].Deleted = true
+			m.audit = append(m.audit, dbComments.AuditEntry{
+				CommentID: c.ID,
+				NewsID:    m.comments[i].NewsID,
+				Action:    dbComments.ActionDelete,
+				Actor:     actor,
+				Reason:    reason,
+				Content:   m.comments[i].Content,
+			})
+			return m.comments[i].NewsID, nil
+		}
+	}
+	return 0, dbComments.ErrNotFound
+}
+
+func (m *memComments) Audit(ctx context.Context, f dbComments.AuditFilter) ([]dbComments.AuditEntry, error) {
+	return m.audit, nil
+}
+
+func TestDeleteComment(t *testing.T) {
+	comments := &memComments{comments: []dbComments.Comment{
+		{ID: 1, NewsID: 5, Content: "Отличная новость"},
+		{ID: 2, NewsID: 5, Content: "Реклама казино"},
+	}}
+	api := New(service.New(countingNews{}, comments), nil, "secret")
+	do := func(method, target, token string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		api.Router().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: статус %d: %s", method, target, rec.Code, rec.Body)
+		}
+		return rec
+	}
+
+	do(http.MethodDelete, "/api/v1/comments/2?reason=spam", "")
+
+	// удалённый комментарий остаётся в обсуждении без текста
+	var got []dbComments.Comment
+	if err := json.NewDecoder(do(http.MethodGet, "/api/v1/news/5/comments", "").Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := []dbComments.Comment{
+		{ID: 1, NewsID: 5, Content: "Отличная новость"},
+		{ID: 2, NewsID: 5, Content: dbComments.RemovedContent, Deleted: true},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("комментарии %+v, ожидались %+v", got, want)
+	}
+
+	// причина и текст удалённого комментария видны в журнале
+	var entries []dbComments.AuditEntry
+	if err := json.NewDecoder(do(http.MethodGet, "/api/v1/admin/comments/audit?comment_id=2", "secret").Body).Decode(&entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("записей в журнале %d, ожидалась 1", len(entries))
+	}
+	e := entries[0]
+	if e.Action != dbComments.ActionDelete || e.Reason != "spam" || e.Content != "Реклама казино" || e.Actor != "ip:192.0.2.1" {
+		t.Errorf("запись журнала %+v", e)
+	}
+}
+
+func TestEditCommentRequiresAdmin(t *testing.T) {
+	comments := &memComments{comments: []dbComments.Comment{{ID: 1, NewsID: 5, Content: "Отличная новость"}}}
+	api := New(service.New(countingNews{}, comments), nil, "secret")
+	tests := []struct {
+		target string
+		status int
+	}{
+		{"/api/v1/admin/comments/1", http.StatusUnauthorized},
+		// прежний открытый адрес больше не меняет комментарии
+		{"/api/v1/comments/1", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPatch, tt.target, strings.NewReader(`{"content": "Реклама казино"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		api.Router().ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("PATCH %s без токена: статус %d, ожидался %d", tt.target, rec.Code, tt.status)
+		}
+	}
+	if comments.comments[0].Content != "Отличная новость" {
+		t.Errorf("комментарий изменён без токена: %+v", comments.comments[0])
+	}
+}
@@ -0,0 +1,123 @@
+// Пакет dedup помогает находить одну и ту же новость, пришедшую из разных
+// лент: приводит ссылки к каноническому виду и считает simhash текста,
+// по которому похожие тексты находятся без точного совпадения.
+package dedup
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Параметры ссылок, которые добавляют счётчики и рассылки.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "yclid": true, "dclid": true, "msclkid": true,
+	"_openstat": true, "mc_cid": true, "mc_eid": true, "ref": true, "ref_src": true,
+	"igshid": true, "spm": true,
+}
+
+// Поддомены мобильных версий сайтов.
+var mobilePrefixes = []string{"m.", "mobile.", "amp.", "www."}
+
+// CanonicalURL приводит ссылку к виду, одинаковому для всех лент: без
+// параметров отслеживания (utm_* и другие), фрагмента, мобильного
+// поддомена и www, порта по умолчанию и завершающей косой черты.
+// Оставшиеся параметры упорядочиваются. Неразобранная ссылка
+// возвращается без изменений.
+func CanonicalURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return raw
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, p := range mobilePrefixes {
+		host = strings.TrimPrefix(host, p)
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	u.Host = host
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	q := u.Query()
+	for k := range q {
+		if strings.HasPrefix(strings.ToLower(k), "utm_") || trackingParams[strings.ToLower(k)] {
+			q.Del(k)
+		}
+	}
+	u.RawQuery = encodeSorted(q)
+
+	if len(u.Path) > 1 {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawPath = ""
+	}
+	if u.Path == "/" {
+		u.Path = ""
+	}
+	return u.String()
+}
+
+// encodeSorted кодирует параметры по алфавиту, как url.Values.Encode,
+// но и значения одного параметра тоже упорядочиваются.
+func encodeSorted(q url.Values) string {
+	for _, v := range q {
+		sort.Strings(v)
+	}
+	return q.Encode()
+}
+
+// Наименьшее число слов, для которого simhash имеет смысл: у коротких
+// текстов слишком много случайных совпадений.
+const minWords = 8
+
+// Simhash возвращает 64-битный simhash текста по парам соседних слов.
+// У похожих текстов хеши отличаются в нескольких битах. Для текстов
+// короче minWords слов возвращает 0.
+func Simhash(text string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) < minWords {
+		return 0
+	}
+	var v [64]int
+	for i := 0; i+1 < len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(words[i]))
+		h.Write([]byte{' '})
+		h.Write([]byte(words[i+1]))
+		sum := h.Sum64()
+		for b := 0; b < 64; b++ {
+			if sum&(1<<b) != 0 {
+				v[b]++
+			} else {
+				v[b]--
+			}
+		}
+	}
+	var hash uint64
+	for b := 0; b < 64; b++ {
+		if v[b] > 0 {
+			hash |= 1 << b
+		}
+	}
+	return hash
+}
+
+// MaxDistance - наибольшее расстояние между хешами одной и той же
+// новости. У случайных текстов расстояние около 32.
+const MaxDistance = 8
+
+// Distance возвращает число различающихся битов двух хешей.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -0,0 +1,46 @@
+package dedup
+
+import "testing"
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://habr.com/ru/articles/924512/?utm_campaign=924512&utm_source=habrahabr&utm_medium=rss",
+			"https://habr.com/ru/articles/924512"},
+		{"https://habr.com/ru/articles/924512/?utm_source=habrahabr&utm_medium=rss&utm_campaign=924512#habracut",
+			"https://habr.com/ru/articles/924512"},
+		{"http://m.habr.com/ru/articles/924512", "https://habr.com/ru/articles/924512"},
+		{"https://WWW.Example.com:443/a?b=2&a=1&fbclid=x", "https://example.com/a?a=1&b=2"},
+		{"https://example.com:8080/", "https://example.com:8080"},
+		{"https://mobile.example.com/news?id=5&ref=rss", "https://example.com/news?id=5"},
+		{"/relative/link", "/relative/link"},
+	}
+	for _, tt := range tests {
+		if got := CanonicalURL(tt.in); got != tt.want {
+			t.Errorf("CanonicalURL(%q) = %q, ожидалось %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSimhash(t *testing.T) {
+	text := "Рассказываем, как переносили сервис с Python на Go, что сломалось по дороге и какие профили помогли найти узкие места"
+	// та же новость из другой ленты: другая пунктуация и одно слово
+	same := "Рассказываем как переносили сервис с Python на Go: что сломалось по дороге и какие профили помогли найти узкие места!"
+	edited := "Рассказываем, как переносили сервис с Python на Go, что сломалось по дороге и какие профили помогли найти все узкие места"
+	other := "Разбираем BRIN, GIN и частичные индексы PostgreSQL на примерах из жизни и сравниваем их скорость с обычными B-tree индексами"
+
+	h := Simhash(text)
+	if d := Distance(h, Simhash(same)); d != 0 {
+		t.Errorf("расстояние до той же новости %d, ожидалось 0", d)
+	}
+	if d := Distance(h, Simhash(edited)); d > MaxDistance {
+		t.Errorf("расстояние до правленой новости %d, ожидалось не больше %d", d, MaxDistance)
+	}
+	if d := Distance(h, Simhash(other)); d < 16 {
+		t.Errorf("расстояние до другой новости %d, ожидалось не меньше 16", d)
+	}
+	if Simhash("Короткий заголовок") != 0 {
+		t.Error("у короткого текста ненулевой хеш")
+	}
+}
@@ -0,0 +1,200 @@
+// Пакет events - шина событий внутри процесса. В шину публикуются новые
+// новости и изменения комментариев, потоковые API и веб-хуки на них подписываются.
+package events
+
+import (
+	"strings"
+	"sync"
+
+	dbComments "Skillfactory-APIGateway/comments/storage"
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+// Типы событий.
+const (
+	PostCreated    = "post.created"
+	CommentCreated = "comment.created"
+	CommentDeleted = "comment.deleted"
+)
+
+// Размер буфера подписчика сверх событий, отданных при возобновлении.
+const subscriberBuffer = 64
+
+// Event - событие шины.
+type Event struct {
+	// ID - порядковый номер события, растёт монотонно в пределах процесса.
+	ID   uint64
+	Type string
+	// Post заполнена для событий о новостях.
+	Post storage.Post
+	// Comment заполнен для событий о комментариях.
+	Comment dbComments.Comment
+}
+
+// Bus - шина событий. Хранит последние события, чтобы подписчик
+// мог возобновить поток после разрыва соединения.
+type Bus struct {
+	mu      sync.Mutex
+	lastID  uint64
+	history []Event
+	size    int
+	subs    map[*Subscription]struct{}
+}
+
+// NewBus создаёт шину, которая хранит historySize последних событий.
+func NewBus(historySize int) *Bus {
+	return &Bus{
+		size: historySize,
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription - подписка на события шины.
+type Subscription struct {
+	// C - канал событий. Закрывается при отписке.
+	C <-chan Event
+
+	c    chan Event
+	bus  *Bus
+	once sync.Once
+
+	// для подписки без пропусков: очередь непрочитанных событий
+	// под bus.mu, сигнал о новых событиях и сигнал отписки
+	lossless bool
+	queue    []Event
+	wake     chan struct{}
+	done     chan struct{}
+}
+
+// PublishPosts публикует событие о каждой добавленной новости.
+func (b *Bus) PublishPosts(posts []storage.Post) {
+	events := make([]Event, 0, len(posts))
+	for _, p := range posts {
+		events = append(events, Event{Type: PostCreated, Post: p})
+	}
+	b.Publish(events...)
+}
+
+// Publish публикует события, присваивая им номера.
+func (b *Bus) Publish(events ...Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, e := range events {
+		b.lastID++
+		e.ID = b.lastID
+		b.history = append(b.history, e)
+		if len(b.history) > b.size {
+			b.history = b.history[len(b.history)-b.size:]
+		}
+		for s := range b.subs {
+			if s.lossless {
+				s.queue = append(s.queue, e)
+				select {
+				case s.wake <- struct{}{}:
+				default:
+				}
+				continue
+			}
+			select {
+			case s.c <- e:
+			default:
+				// подписчик не успевает читать, событие пропускается
+			}
+		}
+	}
+}
+
+// Subscribe подписывает на события. Если lastEventID больше нуля,
+// сначала отдаются сохранённые события с большим номером.
+func (b *Bus) Subscribe(lastEventID uint64) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []Event
+	if lastEventID > 0 {
+		for _, e := range b.history {
+			if e.ID > lastEventID {
+				replay = append(replay, e)
+			}
+		}
+	}
+	c := make(chan Event, len(replay)+subscriberBuffer)
+	for _, e := range replay {
+		c <- e
+	}
+	s := &Subscription{C: c, c: c, bus: b}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// SubscribeAll подписывает на новые события без пропусков: события,
+// которые подписчик ещё не прочитал, копятся в памяти, а публикация
+// их не ждёт. Подходит для подписчиков, которые быстро сохраняют
+// события, например в очередь доставки веб-хуков.
+func (b *Bus) SubscribeAll() *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := make(chan Event)
+	s := &Subscription{
+		C:        c,
+		c:        c,
+		bus:      b,
+		lossless: true,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	b.subs[s] = struct{}{}
+	go s.pump()
+	return s
+}
+
+// pump передаёт события из очереди подписки в канал по порядку,
+// пока подписка не будет закрыта.
+func (s *Subscription) pump() {
+	defer close(s.c)
+	for {
+		s.bus.mu.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.bus.mu.Unlock()
+		for _, e := range queue {
+			select {
+			case s.c <- e:
+			case <-s.done:
+				return
+			}
+		}
+		if len(queue) > 0 {
+			continue
+		}
+		select {
+		case <-s.wake:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Close отписывает от шины и закрывает канал событий.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+		if s.lossless {
+			// канал закроет pump
+			close(s.done)
+			return
+		}
+		close(s.c)
+	})
+}
+
+// MatchTitle сообщает, содержит ли заголовок новости подстроку query
+// без учёта регистра. Пустой query подходит для любой новости.
+func MatchTitle(p storage.Post, query string) bool {
+	if query == "" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(p.Title), strings.ToLower(query))
+}
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"testing"
+
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+func TestBus_Resume(t *testing.T) {
+	b := NewBus(3)
+	b.PublishPosts([]storage.Post{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}})
+
+	// хранятся только 3 последних события: 2, 3, 4
+	sub := b.Subscribe(2)
+	defer sub.Close()
+	b.PublishPosts([]storage.Post{{ID: 5}})
+
+	var got []uint64
+	for len(got) < 3 {
+		e := <-sub.C
+		got = append(got, e.ID)
+	}
+	want := []uint64{3, 4, 5}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("получены события %v, ожидались %v", got, want)
+		}
+	}
+}
+
+func TestBus_NewOnly(t *testing.T) {
+	b := NewBus(10)
+	b.PublishPosts([]storage.Post{{ID: 1}})
+	sub := b.Subscribe(0)
+	b.PublishPosts([]storage.Post{{ID: 2, Title: "new"}})
+
+	e := <-sub.C
+	if e.Post.ID != 2 || e.Type != PostCreated {
+		t.Errorf("получено событие %+v, ожидалась новость 2", e)
+	}
+	sub.Close()
+	if _, ok := <-sub.C; ok {
+		t.Error("канал должен быть закрыт после отписки")
+	}
+	// публикация после отписки не должна паниковать
+	b.PublishPosts([]storage.Post{{ID: 3}})
+}
+
+func TestBus_SubscribeAll(t *testing.T) {
+	b := NewBus(10)
+	sub := b.SubscribeAll()
+
+	// событий больше буфера обычной подписки, а подписчик пока не читает
+	const n = 3 * subscriberBuffer
+	posts := make([]storage.Post, n)
+	for i := range posts {
+		posts[i].ID = i + 1
+	}
+	b.PublishPosts(posts)
+
+	for i := 1; i <= n; i++ {
+		if e := <-sub.C; e.Post.ID != i {
+			t.Fatalf("получена новость %d, ожидалась %d", e.Post.ID, i)
+		}
+	}
+	sub.Close()
+	for range sub.C {
+	}
+	b.PublishPosts([]storage.Post{{ID: n + 1}})
+}
+
+func TestMatchTitle(t *testing.T) {
+	p := storage.Post{Title: "Go 1.24: что нового"}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"go", true},
+		{"ЧТО", true},
+		{"rust", false},
+	}
+	for _, tt := range tests {
+		if got := MatchTitle(p, tt.query); got != tt.want {
+			t.Errorf("MatchTitle(%q) = %v, ожидалось %v", tt.query, got, tt.want)
+		}
+	}
+}
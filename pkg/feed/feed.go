@@ -0,0 +1,204 @@
+// Пакет feed формирует из новостей ленты в форматах RSS 2.0, Atom и JSON Feed.
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"time"
+
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+// Feed - исходящая лента новостей.
+type Feed struct {
+	Title       string
+	Description string
+	// Link - адрес сайта.
+	Link string
+	// FeedURL - адрес самой ленты.
+	FeedURL string
+	Items   []storage.Post
+}
+
+// Updated возвращает время самой свежей новости ленты.
+func (f Feed) Updated() time.Time {
+	var max int64
+	for _, p := range f.Items {
+		if p.PubTime > max {
+			max = p.PubTime
+		}
+	}
+	return time.Unix(max, 0).UTC()
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS записывает ленту в формате RSS 2.0.
+func RSS(w io.Writer, f Feed) error {
+	doc := rss{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       f.Title,
+			Link:        f.Link,
+			Description: f.Description,
+			Self:        atomLink{Href: f.FeedURL, Rel: "self", Type: "application/rss+xml"},
+			Items:       make([]rssItem, 0, len(f.Items)),
+		},
+	}
+	if len(f.Items) > 0 {
+		doc.Channel.LastBuildDate = f.Updated().Format(time.RFC1123Z)
+	}
+	for _, p := range f.Items {
+		item := rssItem{
+			Title:       p.Title,
+			Link:        p.Link,
+			Description: p.Content,
+			GUID:        rssGUID{IsPermaLink: true, Value: p.Link},
+		}
+		if p.PubTime > 0 {
+			item.PubDate = time.Unix(p.PubTime, 0).UTC().Format(time.RFC1123Z)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return writeXML(w, doc)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string   `xml:"title"`
+	ID        string   `xml:"id"`
+	Link      atomLink `xml:"link"`
+	Updated   string   `xml:"updated"`
+	Published string   `xml:"published,omitempty"`
+	Summary   atomText `xml:"summary"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Atom записывает ленту в формате Atom 1.0.
+func Atom(w io.Writer, f Feed) error {
+	doc := atomFeed{
+		Title:   f.Title,
+		ID:      f.FeedURL,
+		Updated: f.Updated().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.FeedURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: f.Link, Rel: "alternate", Type: "text/html"},
+		},
+		Entries: make([]atomEntry, 0, len(f.Items)),
+	}
+	for _, p := range f.Items {
+		t := time.Unix(p.PubTime, 0).UTC().Format(time.RFC3339)
+		doc.Entries = append(doc.Entries, atomEntry{
+			Title:     p.Title,
+			ID:        p.Link,
+			Link:      atomLink{Href: p.Link, Rel: "alternate"},
+			Updated:   t,
+			Published: t,
+			Summary:   atomText{Type: "text", Value: p.Content},
+		})
+	}
+	return writeXML(w, doc)
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+// Версия формата JSON Feed.
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string `json:"id"`
+	URL           string `json:"url,omitempty"`
+	Title         string `json:"title,omitempty"`
+	ContentText   string `json:"content_text"`
+	DatePublished string `json:"date_published,omitempty"`
+}
+
+// JSON записывает ленту в формате JSON Feed 1.1.
+func JSON(w io.Writer, f Feed) error {
+	doc := jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     f.FeedURL,
+		Description: f.Description,
+		Items:       make([]jsonFeedItem, 0, len(f.Items)),
+	}
+	for _, p := range f.Items {
+		item := jsonFeedItem{
+			ID:          p.Link,
+			URL:         p.Link,
+			Title:       p.Title,
+			ContentText: p.Content,
+		}
+		if p.PubTime > 0 {
+			item.DatePublished = time.Unix(p.PubTime, 0).UTC().Format(time.RFC3339)
+		}
+		doc.Items = append(doc.Items, item)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
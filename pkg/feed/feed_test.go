@@ -0,0 +1,111 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+var testFeed = Feed{
+	Title:   "GoNews: Go",
+	Link:    "http://localhost/",
+	FeedURL: "http://localhost/feed.rss?s=Go",
+	Items: []storage.Post{
+		{ID: 2, Title: "Go 1.23 & <generics>", Content: "Новая версия", PubTime: 1718000000, Link: "https://habr.com/ru/articles/2/"},
+		{ID: 1, Title: "Go 1.22", Content: "Прошлая версия", PubTime: 1710000000, Link: "https://habr.com/ru/articles/1/"},
+	},
+}
+
+func TestRSS(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RSS(&buf, testFeed); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Channel struct {
+			Title string `xml:"title"`
+			Items []struct {
+				Title   string `xml:"title"`
+				PubDate string `xml:"pubDate"`
+				GUID    string `xml:"guid"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("лента не является корректным XML: %v\n%s", err, buf.String())
+	}
+	if len(doc.Channel.Items) != 2 {
+		t.Fatalf("элементов %d, ожидалось 2", len(doc.Channel.Items))
+	}
+	item := doc.Channel.Items[0]
+	if item.Title != testFeed.Items[0].Title {
+		t.Errorf("title = %q", item.Title)
+	}
+	if item.PubDate != "Mon, 10 Jun 2024 06:13:20 +0000" {
+		t.Errorf("pubDate = %q", item.PubDate)
+	}
+	if item.GUID != testFeed.Items[0].Link {
+		t.Errorf("guid = %q", item.GUID)
+	}
+}
+
+func TestAtom(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Atom(&buf, testFeed); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		Updated string   `xml:"updated"`
+		Entries []struct {
+			ID   string `xml:"id"`
+			Link struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("лента не является корректным XML: %v\n%s", err, buf.String())
+	}
+	if doc.Updated != "2024-06-10T06:13:20Z" {
+		t.Errorf("updated = %q", doc.Updated)
+	}
+	if len(doc.Entries) != 2 || doc.Entries[1].Link.Href != testFeed.Items[1].Link {
+		t.Errorf("записи: %+v", doc.Entries)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := JSON(&buf, testFeed); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["version"] != jsonFeedVersion {
+		t.Errorf("version = %v", doc["version"])
+	}
+	items := doc["items"].([]interface{})
+	if len(items) != 2 {
+		t.Fatalf("элементов %d, ожидалось 2", len(items))
+	}
+	if got := items[0].(map[string]interface{})["date_published"]; got != "2024-06-10T06:13:20Z" {
+		t.Errorf("date_published = %v", got)
+	}
+}
+
+func TestEmptyFeed(t *testing.T) {
+	var buf bytes.Buffer
+	if err := JSON(&buf, Feed{Title: "GoNews"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"items": []`) {
+		t.Errorf("пустая лента должна содержать пустой список items:\n%s", buf.String())
+	}
+}
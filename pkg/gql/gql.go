@@ -0,0 +1,353 @@
+// Пакет gql - GraphQL API приложения GoNews.
+package gql
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"log"
+	"net/http"
+
+	dbComments "Skillfactory-APIGateway/comments/storage"
+	"Skillfactory-APIGateway/pkg/service"
+	"Skillfactory-APIGateway/pkg/storage"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+// Схема GraphQL.
+//
+//go:embed schema.graphql
+var schemaString string
+
+// Ключ загрузчика комментариев в контексте запроса.
+type loaderKey struct{}
+
+// Handler возвращает обработчик запросов GraphQL. На каждый запрос
+// создаётся свой загрузчик комментариев.
+func Handler(svc *service.Service) http.Handler {
+	schema := graphql.MustParseSchema(schemaString, &resolver{svc: svc})
+	h := &relay.Handler{Schema: schema}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := newCommentsLoader(r.Context(), svc.CommentsByNews)
+		ctx := context.WithValue(r.Context(), loaderKey{}, l)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func loaderFrom(ctx context.Context) *commentsLoader {
+	return ctx.Value(loaderKey{}).(*commentsLoader)
+}
+
+// Корневой резолвер запросов и мутаций.
+type resolver struct {
+	svc *service.Service
+}
+
+func (r *resolver) News(ctx context.Context, args struct {
+	Page     int32
+	SourceID int32
+	Tag      string
+}) (*newsPageResolver, error) {
+	posts, pagination, err := r.svc.Latest(ctx, storage.Filter{
+		SourceID: int(args.SourceID),
+		Tag:      storage.NormalizeTag(args.Tag),
+	}, storage.Sort{}, int(args.Page), 0)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return newNewsPage(ctx, posts, pagination), nil
+}
+
+func (r *resolver) Search(ctx context.Context, args struct {
+	Query    string
+	Page     int32
+	SourceID int32
+	Tag      string
+}) (*newsPageResolver, error) {
+	if args.Query == "" {
+		return nil, &apiError{code: "bad_request", message: "query must not be empty"}
+	}
+	posts, pagination, err := r.svc.Latest(ctx, storage.Filter{
+		Search:   args.Query,
+		SourceID: int(args.SourceID),
+		Tag:      storage.NormalizeTag(args.Tag),
+	}, storage.Sort{}, int(args.Page), 0)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return newNewsPage(ctx, posts, pagination), nil
+}
+
+func (r *resolver) Post(ctx context.Context, args struct{ ID int32 }) (*postResolver, error) {
+	p, err := r.svc.Post(ctx, int(args.ID))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &postResolver{p: p}, nil
+}
+
+func (r *resolver) Comments(ctx context.Context, args struct{ NewsID int32 }) ([]*commentResolver, error) {
+	comments, err := loaderFrom(ctx).Load(int(args.NewsID))
+	if err != nil {
+		return nil, toError(err)
+	}
+	return commentResolvers(comments), nil
+}
+
+func (r *resolver) Sources(ctx context.Context) ([]*sourceResolver, error) {
+	sources, err := r.svc.Sources(ctx)
+	if err != nil {
+		return nil, toError(err)
+	}
+	res := make([]*sourceResolver, 0, len(sources))
+	for _, src := range sources {
+		url := src.Link
+		if url == "" {
+			url = src.URL
+		}
+		res = append(res, &sourceResolver{id: src.ID, name: src.Name, url: url})
+	}
+	return res, nil
+}
+
+func (r *resolver) Tags(ctx context.Context) ([]*tagResolver, error) {
+	tags, err := r.svc.Tags(ctx)
+	if err != nil {
+		return nil, toError(err)
+	}
+	res := make([]*tagResolver, 0, len(tags))
+	for _, t := range tags {
+		res = append(res, &tagResolver{t: t})
+	}
+	return res, nil
+}
+
+func (r *resolver) AddComment(ctx context.Context, args struct {
+	NewsID  int32
+	Content string
+}) (*commentResolver, error) {
+	c, err := r.svc.AddComment(ctx, dbComments.Comment{
+		NewsID:  int(args.NewsID),
+		Content: args.Content,
+	})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &commentResolver{c: c}, nil
+}
+
+// Страница новостей.
+type newsPageResolver struct {
+	items      []*postResolver
+	pagination storage.Pagination
+}
+
+// newNewsPage создаёт страницу и заранее регистрирует id новостей
+// в загрузчике, чтобы комментарии ко всем ним загрузились одним запросом.
+func newNewsPage(ctx context.Context, posts []storage.Post, p storage.Pagination) *newsPageResolver {
+	res := &newsPageResolver{pagination: p, items: make([]*postResolver, 0, len(posts))}
+	ids := make([]int, 0, len(posts))
+	for _, post := range posts {
+		res.items = append(res.items, &postResolver{p: post})
+		ids = append(ids, post.ID)
+	}
+	loaderFrom(ctx).Prime(ids...)
+	return res
+}
+
+func (r *newsPageResolver) Items() []*postResolver { return r.items }
+
+func (r *newsPageResolver) Pagination() *paginationResolver {
+	return &paginationResolver{p: r.pagination}
+}
+
+type paginationResolver struct {
+	p storage.Pagination
+}
+
+func (r *paginationResolver) TotalPages() int32   { return int32(r.p.NumOfPages) }
+func (r *paginationResolver) CurrentPage() int32  { return int32(r.p.Page) }
+func (r *paginationResolver) ItemsPerPage() int32 { return int32(r.p.Limit) }
+func (r *paginationResolver) TotalItems() int32   { return int32(r.p.TotalItems) }
+
+// Новость.
+type postResolver struct {
+	p storage.Post
+}
+
+func (r *postResolver) ID() int32        { return int32(r.p.ID) }
+func (r *postResolver) Title() string    { return r.p.Title }
+func (r *postResolver) Content() string  { return r.p.Content }
+func (r *postResolver) PubTime() float64 { return float64(r.p.PubTime) }
+func (r *postResolver) Link() string     { return r.p.Link }
+func (r *postResolver) GUID() string     { return r.p.GUID }
+func (r *postResolver) Author() string   { return r.p.Author }
+func (r *postResolver) Image() string    { return r.p.Image }
+func (r *postResolver) RawHTML() string  { return r.p.RawHTML }
+func (r *postResolver) Summary() string  { return r.p.Summary }
+
+func (r *postResolver) ContentHTML() string  { return r.p.ContentHTML }
+func (r *postResolver) CanonicalURL() string { return r.p.CanonicalURL }
+
+func (r *postResolver) Alternates() []*alternateResolver {
+	res := make([]*alternateResolver, 0, len(r.p.Alternates))
+	for _, a := range r.p.Alternates {
+		res = append(res, &alternateResolver{a: a})
+	}
+	return res
+}
+
+func (r *postResolver) Categories() []string {
+	if r.p.Categories == nil {
+		return []string{}
+	}
+	return r.p.Categories
+}
+
+func (r *postResolver) Tags() []string {
+	if r.p.Tags == nil {
+		return []string{}
+	}
+	return r.p.Tags
+}
+
+func (r *postResolver) Enclosures() []*enclosureResolver {
+	res := make([]*enclosureResolver, 0, len(r.p.Enclosures))
+	for _, e := range r.p.Enclosures {
+		res = append(res, &enclosureResolver{e: e})
+	}
+	return res
+}
+
+func (r *postResolver) Source() *sourceResolver {
+	if r.p.SourceID == 0 {
+		return nil
+	}
+	return &sourceResolver{id: r.p.SourceID, name: r.p.SourceName, url: r.p.SourceURL}
+}
+
+func (r *postResolver) Comments(ctx context.Context, args struct{ Limit *int32 }) ([]*commentResolver, error) {
+	comments, err := loaderFrom(ctx).Load(r.p.ID)
+	if err != nil {
+		return nil, toError(err)
+	}
+	if args.Limit != nil && *args.Limit >= 0 && int(*args.Limit) < len(comments) {
+		comments = comments[:*args.Limit]
+	}
+	return commentResolvers(comments), nil
+}
+
+// CommentCount - число комментариев без удалённых, как в REST API.
+func (r *postResolver) CommentCount(ctx context.Context) (int32, error) {
+	comments, err := loaderFrom(ctx).Load(r.p.ID)
+	if err != nil {
+		return 0, toError(err)
+	}
+	var n int32
+	for _, c := range comments {
+		if !c.Deleted {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Вложение новости.
+type enclosureResolver struct {
+	e storage.Enclosure
+}
+
+func (r *enclosureResolver) URL() string     { return r.e.URL }
+func (r *enclosureResolver) Type() string    { return r.e.Type }
+func (r *enclosureResolver) Length() float64 { return float64(r.e.Length) }
+
+// Повтор новости из другой ленты.
+type alternateResolver struct {
+	a storage.Alternate
+}
+
+func (r *alternateResolver) ID() int32    { return int32(r.a.ID) }
+func (r *alternateResolver) Link() string { return r.a.Link }
+
+func (r *alternateResolver) Source() *sourceResolver {
+	if r.a.SourceID == 0 {
+		return nil
+	}
+	return &sourceResolver{id: r.a.SourceID, name: r.a.SourceName}
+}
+
+// Лента-источник.
+type sourceResolver struct {
+	id        int
+	name, url string
+}
+
+func (r *sourceResolver) ID() int32    { return int32(r.id) }
+func (r *sourceResolver) Name() string { return r.name }
+func (r *sourceResolver) URL() string  { return r.url }
+
+// Комментарий.
+type commentResolver struct {
+	c dbComments.Comment
+}
+
+func (r *commentResolver) ID() int32        { return int32(r.c.ID) }
+func (r *commentResolver) NewsID() int32    { return int32(r.c.NewsID) }
+func (r *commentResolver) Content() string  { return r.c.Content }
+func (r *commentResolver) PubTime() float64 { return float64(r.c.PubTime) }
+func (r *commentResolver) Deleted() bool    { return r.c.Deleted }
+
+func commentResolvers(comments []dbComments.Comment) []*commentResolver {
+	res := make([]*commentResolver, 0, len(comments))
+	for _, c := range comments {
+		res = append(res, &commentResolver{c: c})
+	}
+	return res
+}
+
+// apiError - ошибка GraphQL с машиночитаемым кодом в extensions.
+// Коды совпадают с кодами ошибок HTTP API.
+type apiError struct {
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string { return e.message }
+
+func (e *apiError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+// toError сопоставляет ошибку сервиса с ошибкой GraphQL.
+// Текст внутренних ошибок клиенту не отдаётся, только в журнал.
+func toError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, dbComments.ErrNotFound):
+		return &apiError{code: "not_found", message: err.Error()}
+	case errors.Is(err, storage.ErrInvalidArgument), errors.Is(err, dbComments.ErrInvalidArgument):
+		return &apiError{code: "bad_request", message: err.Error()}
+	case errors.Is(err, service.ErrForbiddenContent):
+		return &apiError{code: "forbidden_content", message: "comment contains forbidden words"}
+	case errors.Is(err, service.ErrCensorshipUnavailable):
+		log.Println(err)
+		return &apiError{code: "upstream_unavailable", message: "censorship service is unavailable"}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &apiError{code: "timeout", message: "storage did not respond in time"}
+	default:
+		log.Printf("ошибка хранилища: %v", err)
+		return &apiError{code: "internal_error", message: "internal server error"}
+	}
+}
+
+// Тег с числом новостей.
+type tagResolver struct {
+	t storage.Tag
+}
+
+func (r *tagResolver) Name() string { return r.t.Name }
+func (r *tagResolver) Count() int32 { return int32(r.t.Count) }
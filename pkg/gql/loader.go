@@ -0,0 +1,111 @@
+package gql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	dbComments "Skillfactory-APIGateway/comments/storage"
+)
+
+// Время ожидания, в течение которого запросы комментариев
+// собираются в один пакет.
+const batchWait = 2 * time.Millisecond
+
+// fetchFunc получает комментарии к нескольким новостям одним запросом.
+type fetchFunc func(ctx context.Context, newsIDs []int) (map[int][]dbComments.Comment, error)
+
+// commentsLoader собирает запросы комментариев к разным новостям
+// в пакеты, чтобы страница новостей не порождала N+1 запросов к БД.
+// Загрузчик создаётся на каждый запрос GraphQL и кэширует результаты.
+type commentsLoader struct {
+	ctx   context.Context
+	fetch fetchFunc
+
+	mu      sync.Mutex
+	pending map[int]struct{}
+	batch   *batch
+	results map[int]loadResult
+}
+
+type loadResult struct {
+	comments []dbComments.Comment
+	err      error
+}
+
+// Пакет запросов, ожидающих загрузки.
+type batch struct {
+	done chan struct{}
+}
+
+func newCommentsLoader(ctx context.Context, fetch fetchFunc) *commentsLoader {
+	return &commentsLoader{
+		ctx:     ctx,
+		fetch:   fetch,
+		pending: make(map[int]struct{}),
+		results: make(map[int]loadResult),
+	}
+}
+
+// Prime добавляет id новостей в следующий пакет, не запуская загрузку.
+// Так комментарии ко всей странице новостей загружаются одним запросом.
+func (l *commentsLoader) Prime(newsIDs ...int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range newsIDs {
+		if _, ok := l.results[id]; !ok {
+			l.pending[id] = struct{}{}
+		}
+	}
+}
+
+// Load возвращает комментарии к новости.
+func (l *commentsLoader) Load(newsID int) ([]dbComments.Comment, error) {
+	l.mu.Lock()
+	if r, ok := l.results[newsID]; ok {
+		l.mu.Unlock()
+		return r.comments, r.err
+	}
+	l.pending[newsID] = struct{}{}
+	b := l.batch
+	if b == nil {
+		b = &batch{done: make(chan struct{})}
+		l.batch = b
+		time.AfterFunc(batchWait, func() { l.dispatch(b) })
+	}
+	l.mu.Unlock()
+
+	<-b.done
+
+	// id добавлен в pending до запуска пакета b,
+	// поэтому результат к этому моменту уже есть
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r := l.results[newsID]
+	return r.comments, r.err
+}
+
+// dispatch загружает все накопленные id одним запросом.
+func (l *commentsLoader) dispatch(b *batch) {
+	l.mu.Lock()
+	ids := make([]int, 0, len(l.pending))
+	for id := range l.pending {
+		ids = append(ids, id)
+	}
+	l.pending = make(map[int]struct{})
+	l.batch = nil
+	l.mu.Unlock()
+
+	comments, err := l.fetch(l.ctx, ids)
+
+	l.mu.Lock()
+	for _, id := range ids {
+		r := loadResult{comments: comments[id], err: err}
+		if r.comments == nil && err == nil {
+			r.comments = []dbComments.Comment{}
+		}
+		l.results[id] = r
+	}
+	l.mu.Unlock()
+	close(b.done)
+}
@@ -0,0 +1,95 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	dbComments "Skillfactory-APIGateway/comments/storage"
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+func TestCommentsLoader_Batch(t *testing.T) {
+	var calls atomic.Int32
+	fetch := func(ctx context.Context, ids []int) (map[int][]dbComments.Comment, error) {
+		calls.Add(1)
+		res := make(map[int][]dbComments.Comment)
+		for _, id := range ids {
+			res[id] = []dbComments.Comment{{ID: id * 10, NewsID: id}}
+		}
+		return res, nil
+	}
+	l := newCommentsLoader(context.Background(), fetch)
+
+	ids := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	l.Prime(ids...)
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			comments, err := l.Load(id)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(comments) != 1 || comments[0].NewsID != id {
+				t.Errorf("новость %d: получены комментарии %+v", id, comments)
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	// повторная загрузка берётся из кэша
+	if _, err := l.Load(3); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("выполнено %d запросов к БД, ожидался 1", n)
+	}
+}
+
+func TestCommentsLoader_Error(t *testing.T) {
+	wantErr := errors.New("db is down")
+	l := newCommentsLoader(context.Background(), func(ctx context.Context, ids []int) (map[int][]dbComments.Comment, error) {
+		return nil, wantErr
+	})
+	if _, err := l.Load(1); !errors.Is(err, wantErr) {
+		t.Errorf("ошибка = %v, ожидалась %v", err, wantErr)
+	}
+}
+
+func TestCommentsLoader_Empty(t *testing.T) {
+	l := newCommentsLoader(context.Background(), func(ctx context.Context, ids []int) (map[int][]dbComments.Comment, error) {
+		return map[int][]dbComments.Comment{}, nil
+	})
+	comments, err := l.Load(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comments == nil {
+		t.Error("для новости без комментариев ожидался пустой список, а не nil")
+	}
+}
+
+func TestCommentCount(t *testing.T) {
+	l := newCommentsLoader(context.Background(), func(ctx context.Context, ids []int) (map[int][]dbComments.Comment, error) {
+		return map[int][]dbComments.Comment{1: {
+			{ID: 1, NewsID: 1, Content: "Отлично"},
+			{ID: 2, NewsID: 1, Content: dbComments.RemovedContent, Deleted: true},
+			{ID: 3, NewsID: 1, Content: "Согласен"},
+		}}, nil
+	})
+	ctx := context.WithValue(context.Background(), loaderKey{}, l)
+	r := &postResolver{p: storage.Post{ID: 1}}
+	n, err := r.CommentCount(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// удалённые комментарии не считаются
+	if n != 2 {
+		t.Errorf("commentCount = %d, ожидалось 2", n)
+	}
+}
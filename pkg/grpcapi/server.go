@@ -0,0 +1,243 @@
+// Пакет grpcapi - gRPC API приложения GoNews.
+package grpcapi
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=Skillfactory-APIGateway --go-grpc_out=../.. --go-grpc_opt=module=Skillfactory-APIGateway gonews/v1/gonews.proto
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+
+	dbComments "Skillfactory-APIGateway/comments/storage"
+	"Skillfactory-APIGateway/pkg/events"
+	"Skillfactory-APIGateway/pkg/grpcapi/gonewspb"
+	"Skillfactory-APIGateway/pkg/service"
+	"Skillfactory-APIGateway/pkg/storage"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// New создаёт gRPC-сервер с сервисами новостей и комментариев.
+func New(svc *service.Service, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(opts...)
+	gonewspb.RegisterNewsServiceServer(s, &newsServer{svc: svc})
+	gonewspb.RegisterCommentServiceServer(s, &commentServer{svc: svc})
+	return s
+}
+
+// Сервис новостей.
+type newsServer struct {
+	gonewspb.UnimplementedNewsServiceServer
+	svc *service.Service
+}
+
+func (s *newsServer) ListNews(ctx context.Context, req *gonewspb.ListNewsRequest) (*gonewspb.ListNewsResponse, error) {
+	posts, pagination, err := s.svc.Latest(ctx, storage.Filter{
+		SourceID: int(req.GetSourceId()),
+		Tag:      storage.NormalizeTag(req.GetTag()),
+	}, storage.Sort{}, int(req.GetPage()), 0)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toListNewsResponse(posts, pagination), nil
+}
+
+func (s *newsServer) SearchNews(ctx context.Context, req *gonewspb.SearchNewsRequest) (*gonewspb.ListNewsResponse, error) {
+	if req.GetQuery() == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	posts, pagination, err := s.svc.Latest(ctx, storage.Filter{
+		Search:   req.GetQuery(),
+		SourceID: int(req.GetSourceId()),
+		Tag:      storage.NormalizeTag(req.GetTag()),
+	}, storage.Sort{}, int(req.GetPage()), 0)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toListNewsResponse(posts, pagination), nil
+}
+
+func (s *newsServer) GetNews(ctx context.Context, req *gonewspb.GetNewsRequest) (*gonewspb.GetNewsResponse, error) {
+	d, err := s.svc.Detailed(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &gonewspb.GetNewsResponse{
+		News:     toPBPost(d.Post),
+		Comments: toPBComments(d.Comments),
+	}
+	if d.CommentsErr != nil {
+		log.Printf("комментарии к новости %d недоступны: %v", d.Post.ID, d.CommentsErr)
+		resp.Degraded = true
+		resp.CommentsError = "comments are temporarily unavailable"
+	}
+	return resp, nil
+}
+
+func (s *newsServer) WatchNews(req *gonewspb.WatchNewsRequest, stream grpc.ServerStreamingServer[gonewspb.Post]) error {
+	sub := s.svc.Subscribe(0)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case e, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			if e.Type != events.PostCreated || !events.MatchTitle(e.Post, req.GetQuery()) {
+				continue
+			}
+			if err := stream.Send(toPBPost(e.Post)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Сервис комментариев.
+type commentServer struct {
+	gonewspb.UnimplementedCommentServiceServer
+	svc *service.Service
+}
+
+func (s *commentServer) ListComments(ctx context.Context, req *gonewspb.ListCommentsRequest) (*gonewspb.ListCommentsResponse, error) {
+	comments, err := s.svc.Comments(ctx, int(req.GetNewsId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &gonewspb.ListCommentsResponse{Comments: toPBComments(comments)}, nil
+}
+
+func (s *commentServer) AddComment(ctx context.Context, req *gonewspb.AddCommentRequest) (*gonewspb.Comment, error) {
+	c, err := s.svc.AddComment(withActor(ctx), dbComments.Comment{
+		NewsID:  int(req.GetNewsId()),
+		Content: req.GetContent(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toPBComment(c), nil
+}
+
+func (s *commentServer) DeleteComment(ctx context.Context, req *gonewspb.DeleteCommentRequest) (*gonewspb.DeleteCommentResponse, error) {
+	err := s.svc.DeleteComment(withActor(ctx), int(req.GetId()), req.GetReason())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &gonewspb.DeleteCommentResponse{}, nil
+}
+
+// withActor сохраняет в контексте адрес клиента как автора
+// действий с комментариями для журнала.
+func withActor(ctx context.Context) context.Context {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		return service.WithActor(ctx, "grpc:"+host)
+	}
+	return ctx
+}
+
+// toStatus сопоставляет ошибку сервиса с кодом gRPC.
+// Текст внутренних ошибок клиенту не отдаётся, только в журнал.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, dbComments.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, storage.ErrInvalidArgument), errors.Is(err, dbComments.ErrInvalidArgument):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrForbiddenContent):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrCensorshipUnavailable):
+		log.Println(err)
+		return status.Error(codes.Unavailable, "censorship service is unavailable")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "storage did not respond in time")
+	default:
+		log.Printf("ошибка хранилища: %v", err)
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
+
+func toListNewsResponse(posts []storage.Post, p storage.Pagination) *gonewspb.ListNewsResponse {
+	resp := &gonewspb.ListNewsResponse{
+		News: make([]*gonewspb.Post, 0, len(posts)),
+		Pagination: &gonewspb.Pagination{
+			TotalPages:   int32(p.NumOfPages),
+			CurrentPage:  int32(p.Page),
+			ItemsPerPage: int32(p.Limit),
+			TotalItems:   int32(p.TotalItems),
+		},
+	}
+	for _, post := range posts {
+		resp.News = append(resp.News, toPBPost(post))
+	}
+	return resp
+}
+
+func toPBPost(p storage.Post) *gonewspb.Post {
+	post := &gonewspb.Post{
+		Id:           int64(p.ID),
+		Title:        p.Title,
+		Content:      p.Content,
+		PubTime:      p.PubTime,
+		Link:         p.Link,
+		Guid:         p.GUID,
+		Author:       p.Author,
+		Categories:   p.Categories,
+		Image:        p.Image,
+		RawHtml:      p.RawHTML,
+		ContentHtml:  p.ContentHTML,
+		Summary:      p.Summary,
+		CanonicalUrl: p.CanonicalURL,
+		Tags:         p.Tags,
+	}
+	for _, a := range p.Alternates {
+		alt := &gonewspb.Alternate{Id: int64(a.ID), Link: a.Link}
+		if a.SourceID != 0 {
+			alt.Source = &gonewspb.Source{Id: int64(a.SourceID), Name: a.SourceName}
+		}
+		post.Alternates = append(post.Alternates, alt)
+	}
+	for _, e := range p.Enclosures {
+		post.Enclosures = append(post.Enclosures, &gonewspb.Enclosure{
+			Url:    e.URL,
+			Type:   e.Type,
+			Length: e.Length,
+		})
+	}
+	if p.SourceID != 0 {
+		post.Source = &gonewspb.Source{
+			Id:   int64(p.SourceID),
+			Name: p.SourceName,
+			Url:  p.SourceURL,
+		}
+	}
+	return post
+}
+
+func toPBComment(c dbComments.Comment) *gonewspb.Comment {
+	return &gonewspb.Comment{
+		Id:      int64(c.ID),
+		NewsId:  int64(c.NewsID),
+		Content: c.Content,
+		PubTime: c.PubTime,
+		Deleted: c.Deleted,
+	}
+}
+
+func toPBComments(comments []dbComments.Comment) []*gonewspb.Comment {
+	res := make([]*gonewspb.Comment, 0, len(comments))
+	for _, c := range comments {
+		res = append(res, toPBComment(c))
+	}
+	return res
+}
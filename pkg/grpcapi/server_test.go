@@ -0,0 +1,85 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	dbComments "Skillfactory-APIGateway/comments/storage"
+	"Skillfactory-APIGateway/pkg/grpcapi/gonewspb"
+	"Skillfactory-APIGateway/pkg/service"
+	"Skillfactory-APIGateway/pkg/storage"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestWatchNews(t *testing.T) {
+	svc := service.New(nil, nil)
+	lis := bufconn.Listen(1 << 20)
+	srv := New(svc)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := gonewspb.NewNewsServiceClient(conn).WatchNews(ctx, &gonewspb.WatchNewsRequest{Query: "go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// подписка на сервере появляется не сразу, поэтому публикуем,
+	// пока клиент не получит новость
+	go func() {
+		for ctx.Err() == nil {
+			svc.Publish([]storage.Post{
+				{ID: 1, Title: "Rust 2.0"},
+				{ID: 2, Title: "Go 1.24 released"},
+			})
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	p, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.GetId() != 2 {
+		t.Errorf("получена новость %d, ожидалась 2", p.GetId())
+	}
+}
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{storage.ErrNotFound, codes.NotFound},
+		{dbComments.ErrNotFound, codes.NotFound},
+		{fmt.Errorf("%w: id", storage.ErrInvalidArgument), codes.InvalidArgument},
+		{service.ErrForbiddenContent, codes.InvalidArgument},
+		{fmt.Errorf("%w: timeout", service.ErrCensorshipUnavailable), codes.Unavailable},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{errors.New("connection refused"), codes.Internal},
+	}
+	for _, tt := range tests {
+		if got := status.Code(toStatus(tt.err)); got != tt.want {
+			t.Errorf("toStatus(%v) = %v, ожидался %v", tt.err, got, tt.want)
+		}
+	}
+}
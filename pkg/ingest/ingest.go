@@ -0,0 +1,258 @@
+// Пакет ingest - конвейер сбора новостей. Опрос ленты проходит стадии
+// загрузки, разбора, нормализации, фильтрации, отсева повторов и записи
+// в БД. У каждой стадии свой пул обработчиков и ограниченная очередь,
+// поэтому число одновременных загрузок и записей не зависит от числа лент,
+// а переполненная очередь задерживает новые опросы.
+package ingest
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"Skillfactory-APIGateway/pkg/sanitize"
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+// Config - число обработчиков каждой стадии, размер очередей
+// и параметры обработки текста новостей.
+type Config struct {
+	FetchWorkers     int `json:"fetch_workers"`
+	ParseWorkers     int `json:"parse_workers"`
+	NormalizeWorkers int `json:"normalize_workers"`
+	FilterWorkers    int `json:"filter_workers"`
+	DedupeWorkers    int `json:"dedupe_workers"`
+	StoreWorkers     int `json:"store_workers"`
+	// QueueSize - размер очереди перед каждой стадией.
+	QueueSize int `json:"queue_size"`
+	// SeenLinks - сколько последних сохранённых ссылок помнить
+	// для отсева повторов без обращения к БД.
+	SeenLinks int `json:"seen_links"`
+	// SummaryLength - длина краткого текста новости в символах.
+	SummaryLength int `json:"summary_length"`
+	// ImageProxy - префикс адреса прокси для картинок в тексте новостей.
+	ImageProxy string `json:"image_proxy"`
+}
+
+// DefaultConfig - настройки конвейера по умолчанию.
+var DefaultConfig = Config{
+	FetchWorkers:     8,
+	ParseWorkers:     4,
+	NormalizeWorkers: 2,
+	FilterWorkers:    2,
+	DedupeWorkers:    1,
+	StoreWorkers:     2,
+	QueueSize:        64,
+	SeenLinks:        10000,
+	SummaryLength:    300,
+	ImageProxy:       "/api/v1/image?url=",
+}
+
+// withDefaults заменяет незаданные параметры значениями по умолчанию.
+func (c Config) withDefaults() Config {
+	def := func(v *int, d int) {
+		if *v < 1 {
+			*v = d
+		}
+	}
+	def(&c.FetchWorkers, DefaultConfig.FetchWorkers)
+	def(&c.ParseWorkers, DefaultConfig.ParseWorkers)
+	def(&c.NormalizeWorkers, DefaultConfig.NormalizeWorkers)
+	def(&c.FilterWorkers, DefaultConfig.FilterWorkers)
+	def(&c.DedupeWorkers, DefaultConfig.DedupeWorkers)
+	def(&c.StoreWorkers, DefaultConfig.StoreWorkers)
+	def(&c.QueueSize, DefaultConfig.QueueSize)
+	def(&c.SeenLinks, DefaultConfig.SeenLinks)
+	def(&c.SummaryLength, DefaultConfig.SummaryLength)
+	if c.ImageProxy == "" {
+		c.ImageProxy = DefaultConfig.ImageProxy
+	}
+	return c
+}
+
+// FetchFunc загружает ленту по адресу.
+type FetchFunc func(ctx context.Context, url string) ([]byte, error)
+
+// ParseFunc раскодирует ленту, загруженную с адреса url.
+type ParseFunc func(b []byte, url string) (storage.Source, []storage.Post, error)
+
+// Store - хранилище, в которое конвейер записывает ленты и новости.
+type Store interface {
+	SaveSource(ctx context.Context, src storage.Source) (storage.Source, error)
+	StoreNews(news []storage.Post) ([]storage.Post, error)
+}
+
+// Pipeline - конвейер сбора новостей.
+type Pipeline struct {
+	fetch   FetchFunc
+	parse   ParseFunc
+	store   Store
+	publish func([]storage.Post)
+	seen    *recentSet
+
+	sanitizer     sanitize.Sanitizer
+	summaryLength int
+
+	stages []*stage
+}
+
+// Опрос одной ленты, передаётся от стадии к стадии.
+type item struct {
+	ctx       context.Context
+	src       storage.Source
+	fetchedAt time.Time
+	body      []byte
+	feed      storage.Source
+	posts     []storage.Post
+	// done получает результат опроса, когда item покидает конвейер.
+	done chan error
+}
+
+// Стадия конвейера.
+type stage struct {
+	name    string
+	workers int
+	in      chan *item
+	fn      func(*item) error
+
+	processed atomic.Int64
+	failed    atomic.Int64
+	busy      atomic.Int64
+	nanos     atomic.Int64
+}
+
+// New создаёт конвейер. publish получает новости, которых раньше не было в БД.
+func New(cfg Config, fetch FetchFunc, parse ParseFunc, store Store, publish func([]storage.Post)) *Pipeline {
+	cfg = cfg.withDefaults()
+	p := &Pipeline{
+		fetch:   fetch,
+		parse:   parse,
+		store:   store,
+		publish: publish,
+		seen:    newRecentSet(cfg.SeenLinks),
+
+		sanitizer:     sanitize.Sanitizer{ImageProxy: cfg.ImageProxy},
+		summaryLength: cfg.SummaryLength,
+	}
+	add := func(name string, workers int, fn func(*item) error) {
+		p.stages = append(p.stages, &stage{
+			name:    name,
+			workers: workers,
+			in:      make(chan *item, cfg.QueueSize),
+			fn:      fn,
+		})
+	}
+	add("fetch", cfg.FetchWorkers, p.fetchStage)
+	add("parse", cfg.ParseWorkers, p.parseStage)
+	add("normalize", cfg.NormalizeWorkers, p.normalizeStage)
+	add("filter", cfg.FilterWorkers, p.filterStage)
+	add("dedupe", cfg.DedupeWorkers, p.dedupeStage)
+	add("store", cfg.StoreWorkers, p.storeStage)
+	return p
+}
+
+// Run запускает обработчики всех стадий и ждёт отмены контекста.
+func (p *Pipeline) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i, st := range p.stages {
+		var next chan *item
+		if i+1 < len(p.stages) {
+			next = p.stages[i+1].in
+		}
+		for w := 0; w < st.workers; w++ {
+			wg.Add(1)
+			go func(st *stage, next chan *item) {
+				defer wg.Done()
+				st.work(ctx, next)
+			}(st, next)
+		}
+	}
+	wg.Wait()
+}
+
+// Process ставит опрос ленты в очередь и ждёт, пока он пройдёт конвейер.
+// Если очередь заполнена, Process ждёт места в ней. Подходит в качестве
+// scheduler.FetchFunc.
+func (p *Pipeline) Process(ctx context.Context, src storage.Source) error {
+	it := &item{ctx: ctx, src: src, done: make(chan error, 1)}
+	select {
+	case p.stages[0].in <- it:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-it.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// work обрабатывает элементы очереди стадии и передаёт их в очередь next.
+// На последней стадии next равен nil, и опрос завершается.
+func (st *stage) work(ctx context.Context, next chan<- *item) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case it := <-st.in:
+			st.busy.Add(1)
+			start := time.Now()
+			err := st.fn(it)
+			st.nanos.Add(int64(time.Since(start)))
+			st.busy.Add(-1)
+			st.processed.Add(1)
+			if err != nil {
+				st.failed.Add(1)
+			}
+			switch {
+			case err != nil || next == nil:
+				it.done <- err
+			default:
+				select {
+				case next <- it:
+				case <-ctx.Done():
+					it.done <- ctx.Err()
+					return
+				}
+			}
+		}
+	}
+}
+
+// StageMetrics - показатели стадии конвейера.
+type StageMetrics struct {
+	Stage   string `json:"stage"`
+	Workers int    `json:"workers"`
+	// Queued - элементов в очереди, QueueSize - её размер.
+	Queued    int `json:"queued"`
+	QueueSize int `json:"queue_size"`
+	// Busy - занятых обработчиков.
+	Busy      int64 `json:"busy"`
+	Processed int64 `json:"processed"`
+	Failed    int64 `json:"failed"`
+	// AvgMillis - среднее время обработки элемента, мс.
+	AvgMillis float64 `json:"avg_ms"`
+}
+
+// Metrics возвращает показатели всех стадий в порядке их следования.
+func (p *Pipeline) Metrics() []StageMetrics {
+	res := make([]StageMetrics, 0, len(p.stages))
+	for _, st := range p.stages {
+		m := StageMetrics{
+			Stage:     st.name,
+			Workers:   st.workers,
+			Queued:    len(st.in),
+			QueueSize: cap(st.in),
+			Busy:      st.busy.Load(),
+			Processed: st.processed.Load(),
+			Failed:    st.failed.Load(),
+		}
+		if m.Processed > 0 {
+			m.AvgMillis = float64(st.nanos.Load()) / float64(m.Processed) / float64(time.Millisecond)
+		}
+		res = append(res, m)
+	}
+	return res
+}
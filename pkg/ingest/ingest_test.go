@@ -0,0 +1,239 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+// Хранилище в памяти.
+type memStore struct {
+	mu    sync.Mutex
+	links map[string]bool
+	calls int
+}
+
+func (m *memStore) SaveSource(ctx context.Context, src storage.Source) (storage.Source, error) {
+	src.ID = 7
+	return src, nil
+}
+
+func (m *memStore) StoreNews(news []storage.Post) ([]storage.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	var added []storage.Post
+	for _, p := range news {
+		if !m.links[p.Link] {
+			m.links[p.Link] = true
+			added = append(added, p)
+		}
+	}
+	return added, nil
+}
+
+func TestPipeline(t *testing.T) {
+	parse := func(b []byte, url string) (storage.Source, []storage.Post, error) {
+		return storage.Source{Name: " Habr ", URL: url, Link: "https://habr.com/"}, []storage.Post{
+			{Title: "  Go\n 1.23 ", Link: "/ru/articles/1/", Content: "Про Go",
+				RawHTML: `<p>Про <a href="../2/">Go</a></p><script>alert(1)</script>`},
+			{Title: "Go 1.23", Link: "https://habr.com/ru/articles/1/"},
+			{Title: "без ссылки"},
+			{Title: "Rust", Link: "https://habr.com/ru/articles/2/"},
+			// та же ссылка с параметрами отслеживания
+			{Title: "Rust", Link: "https://habr.com/ru/articles/2/?utm_source=rss#comments"},
+		}, nil
+	}
+	fetch := func(ctx context.Context, url string) ([]byte, error) { return []byte("<rss/>"), nil }
+	store := &memStore{links: map[string]bool{}}
+	var published []storage.Post
+	p := New(Config{}, fetch, parse, store, func(posts []storage.Post) { published = append(published, posts...) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	src := storage.Source{ID: 7, URL: "https://habr.com/ru/rss/"}
+	if err := p.Process(ctx, src); err != nil {
+		t.Fatal(err)
+	}
+	if len(published) != 2 {
+		t.Fatalf("опубликовано %d новостей, ожидалось 2: %+v", len(published), published)
+	}
+	got := published[0]
+	if got.Title != "Go 1.23" || got.Link != "https://habr.com/ru/articles/1/" || got.SourceID != 7 || got.SourceName != "Habr" {
+		t.Errorf("новость не нормализована: %+v", got)
+	}
+	wantHTML := `<p>Про <a href="https://habr.com/ru/articles/2/" rel="nofollow noopener noreferrer">Go</a></p>`
+	if got.ContentHTML != wantHTML || got.Summary != "Про Go" {
+		t.Errorf("html %q, краткий текст %q", got.ContentHTML, got.Summary)
+	}
+
+	// повторный опрос не доходит до БД: все ссылки уже сохранены
+	if err := p.Process(ctx, src); err != nil {
+		t.Fatal(err)
+	}
+	if store.calls != 1 {
+		t.Errorf("StoreNews вызван %d раз, ожидался 1", store.calls)
+	}
+
+	for _, m := range p.Metrics() {
+		if m.Processed != 2 || m.Failed != 0 {
+			t.Errorf("стадия %s: %+v", m.Stage, m)
+		}
+	}
+}
+
+func TestPipelinePubTime(t *testing.T) {
+	past := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC).Unix()
+	soon := time.Now().Add(time.Minute).Unix()
+	tests := []struct {
+		name    string
+		pubTime int64
+		rawDate string
+		fetched bool // ожидается время загрузки
+	}{
+		{"дата из ленты", past, "Wed, 01 May 2024 12:00:00 GMT", false},
+		{"в пределах расхождения часов", soon, "", false},
+		{"нет даты", 0, "вчера вечером", true},
+		{"дата из будущего", time.Now().Add(48 * time.Hour).Unix(), "", true},
+	}
+	var posts []storage.Post
+	for i, tt := range tests {
+		posts = append(posts, storage.Post{Title: tt.name, Link: fmt.Sprintf("https://a/%d", i), PubTime: tt.pubTime, RawDate: tt.rawDate})
+	}
+	parse := func(b []byte, url string) (storage.Source, []storage.Post, error) {
+		return storage.Source{URL: url}, posts, nil
+	}
+	fetch := func(ctx context.Context, url string) ([]byte, error) { return nil, nil }
+	var published []storage.Post
+	p := New(Config{}, fetch, parse, &memStore{links: map[string]bool{}}, func(posts []storage.Post) { published = append(published, posts...) })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	start := time.Now().Unix()
+	if err := p.Process(ctx, storage.Source{URL: "https://a/rss"}); err != nil {
+		t.Fatal(err)
+	}
+	end := time.Now().Unix()
+	// в предупреждении - дата из ленты, а не ссылка
+	if want := `https://a/2: неверная дата публикации "вчера вечером"`; !strings.Contains(logs.String(), want) {
+		t.Errorf("в журнале нет %q:\n%s", want, logs.String())
+	}
+	if len(published) != len(tests) {
+		t.Fatalf("опубликовано %d новостей, ожидалось %d", len(published), len(tests))
+	}
+	for i, tt := range tests {
+		got := published[i].PubTime
+		if tt.fetched && (got < start || got > end) {
+			t.Errorf("%s: время %d, ожидалось время загрузки", tt.name, got)
+		}
+		if !tt.fetched && got != tt.pubTime {
+			t.Errorf("%s: время %d, ожидалось %d", tt.name, got, tt.pubTime)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	rules := []storage.TagRule{
+		{Tag: "Go", Keywords: []string{"go", "golang"}},
+		{Tag: "базы данных", Keywords: []string{"PostgreSQL"}},
+		{Tag: "rust", Keywords: []string{"rust"}},
+	}
+	tests := []struct {
+		name string
+		post storage.Post
+		want []string
+	}{
+		{"рубрики", storage.Post{Categories: []string{" Go ", "Open  Source", "go"}}, []string{"go", "open source"}},
+		{"ключевое слово", storage.Post{Title: "Вышел Go 1.23"}, []string{"go"}},
+		{"в тексте без учёта регистра", storage.Post{Content: "Переезд на postgresql."}, []string{"базы данных"}},
+		{"часть слова", storage.Post{Title: "Google и Gopher", Content: "trust"}, nil},
+		{"рубрика и правило", storage.Post{Title: "Rust", Categories: []string{"Rust"}}, []string{"rust"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tags(tt.post, rules); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("теги %q, ожидались %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipelineError(t *testing.T) {
+	failure := errors.New("503")
+	fetch := func(ctx context.Context, url string) ([]byte, error) { return nil, failure }
+	p := New(Config{}, fetch, nil, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	if err := p.Process(ctx, storage.Source{URL: "http://a/rss"}); !errors.Is(err, failure) {
+		t.Fatalf("Process() = %v, ожидалась ошибка загрузки", err)
+	}
+	m := p.Metrics()
+	if m[0].Failed != 1 || m[1].Processed != 0 {
+		t.Errorf("метрики после ошибки: %+v", m)
+	}
+}
+
+func TestPipelineBoundedWorkers(t *testing.T) {
+	var cur, max atomic.Int64
+	fetch := func(ctx context.Context, url string) ([]byte, error) {
+		n := cur.Add(1)
+		defer cur.Add(-1)
+		for {
+			m := max.Load()
+			if n <= m || max.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return nil, errors.New("нет сети")
+	}
+	p := New(Config{FetchWorkers: 2, QueueSize: 1}, fetch, nil, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Process(ctx, storage.Source{URL: "http://a/rss"})
+		}()
+	}
+	wg.Wait()
+	if got := max.Load(); got > 2 {
+		t.Errorf("одновременных загрузок %d, ожидалось не больше 2", got)
+	}
+	if got := p.Metrics()[0].Processed; got != 20 {
+		t.Errorf("загружено %d лент, ожидалось 20", got)
+	}
+}
+
+func TestRecentSet(t *testing.T) {
+	s := newRecentSet(2)
+	s.Add("a")
+	s.Add("b")
+	s.Add("c")
+	if s.Has("a") || !s.Has("b") || !s.Has("c") {
+		t.Error("должны помниться только две последние строки")
+	}
+}
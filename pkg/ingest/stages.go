@@ -0,0 +1,243 @@
+package ingest
+
+import (
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"Skillfactory-APIGateway/pkg/dedup"
+	"Skillfactory-APIGateway/pkg/sanitize"
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+// fetchStage загружает ленту.
+func (p *Pipeline) fetchStage(it *item) error {
+	it.fetchedAt = time.Now()
+	b, err := p.fetch(it.ctx, it.src.URL)
+	if err != nil {
+		return err
+	}
+	it.body = b
+	return nil
+}
+
+// parseStage раскодирует загруженную ленту.
+func (p *Pipeline) parseStage(it *item) error {
+	feed, posts, err := p.parse(it.body, it.src.URL)
+	if err != nil {
+		return err
+	}
+	it.body = nil
+	it.feed, it.posts = feed, posts
+	return nil
+}
+
+// Насколько дата публикации может опережать время загрузки
+// из-за расхождения часов.
+const maxClockSkew = 10 * time.Minute
+
+// normalizeStage убирает лишние пробелы, приводит ссылки к абсолютным
+// относительно сайта ленты, очищает HTML, составляет краткий текст
+// считает ключи для поиска повторов из других лент и назначает теги.
+// Неизвестная дата или дата из будущего заменяется временем загрузки.
+func (p *Pipeline) normalizeStage(it *item) error {
+	it.feed.Name = strings.TrimSpace(it.feed.Name)
+	base, _ := url.Parse(it.feed.Link)
+	if base == nil || !base.IsAbs() {
+		base, _ = url.Parse(it.src.URL)
+	}
+	for i := range it.posts {
+		post := &it.posts[i]
+		post.Title = strings.Join(strings.Fields(post.Title), " ")
+		post.Content = strings.TrimSpace(post.Content)
+		post.Link = resolve(base, strings.TrimSpace(post.Link))
+		post.Image = resolve(base, post.Image)
+		for j := range post.Enclosures {
+			post.Enclosures[j].URL = resolve(base, post.Enclosures[j].URL)
+		}
+		// относительные ссылки в тексте отсчитываются от адреса новости
+		postBase, _ := url.Parse(post.Link)
+		if postBase == nil || !postBase.IsAbs() {
+			postBase = base
+		}
+		post.ContentHTML = p.sanitizer.HTML(post.RawHTML, postBase)
+		post.Summary = sanitize.Summary(post.Content, p.summaryLength)
+		post.CanonicalURL = dedup.CanonicalURL(post.Link)
+		post.Simhash = dedup.Simhash(post.Title + " " + post.Content)
+		post.Tags = tags(*post, it.src.TagRules)
+		if post.PubTime == 0 || post.PubTime > it.fetchedAt.Add(maxClockSkew).Unix() {
+			log.Printf("конвейер: %s: новость %s: неверная дата публикации %q, используется время загрузки",
+				it.src.URL, post.Link, post.RawDate)
+			post.PubTime = it.fetchedAt.Unix()
+		}
+	}
+	return nil
+}
+
+// resolve приводит относительную ссылку к абсолютной.
+func resolve(base *url.URL, link string) string {
+	if u, err := url.Parse(link); err == nil && !u.IsAbs() && link != "" && base != nil {
+		return base.ResolveReference(u).String()
+	}
+	return link
+}
+
+// tags возвращает теги новости: рубрики из ленты и теги правил,
+// ключевое слово которых есть в заголовке или тексте как отдельное слово.
+func tags(post storage.Post, rules []storage.TagRule) []string {
+	var list []string
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		tag = storage.NormalizeTag(tag)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			list = append(list, tag)
+		}
+	}
+	for _, c := range post.Categories {
+		add(c)
+	}
+	text := strings.ToLower(post.Title + " " + post.Content)
+	for _, rule := range rules {
+		for _, kw := range rule.Keywords {
+			if hasWord(text, strings.ToLower(strings.TrimSpace(kw))) {
+				add(rule.Tag)
+				break
+			}
+		}
+	}
+	return list
+}
+
+// hasWord проверяет, что word входит в text целым словом:
+// "go" находится в "язык go 1.23", но не в "google".
+func hasWord(text, word string) bool {
+	if word == "" {
+		return false
+	}
+	for i := 0; ; {
+		j := strings.Index(text[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(text[start:])
+		i = start + size
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// filterStage отбрасывает новости без ссылки или заголовка.
+func (p *Pipeline) filterStage(it *item) error {
+	posts := it.posts[:0]
+	for _, post := range it.posts {
+		if post.Link == "" || post.Title == "" {
+			continue
+		}
+		posts = append(posts, post)
+	}
+	it.posts = posts
+	return nil
+}
+
+// dedupeStage отбрасывает повторы внутри ленты и новости этой ленты,
+// недавно сохранённые в БД. Повторы из других лент доходят до БД, чтобы
+// она записала их как другие источники основной новости.
+func (p *Pipeline) dedupeStage(it *item) error {
+	batch := make(map[string]bool, len(it.posts))
+	posts := it.posts[:0]
+	for _, post := range it.posts {
+		if batch[post.CanonicalURL] || p.seen.Has(seenKey(it, post)) {
+			continue
+		}
+		batch[post.CanonicalURL] = true
+		posts = append(posts, post)
+	}
+	it.posts = posts
+	return nil
+}
+
+// storeStage сохраняет ленту и новости и рассылает новые новости подписчикам.
+func (p *Pipeline) storeStage(it *item) error {
+	feed, err := p.store.SaveSource(it.ctx, it.feed)
+	if err != nil {
+		return err
+	}
+	link := feed.Link
+	if link == "" {
+		link = feed.URL
+	}
+	for i := range it.posts {
+		it.posts[i].SourceID = feed.ID
+		it.posts[i].SourceName = feed.Name
+		it.posts[i].SourceURL = link
+	}
+	if len(it.posts) == 0 {
+		return nil
+	}
+	added, err := p.store.StoreNews(it.posts)
+	if len(added) > 0 {
+		p.publish(added)
+	}
+	if err != nil {
+		return err
+	}
+	for _, post := range it.posts {
+		p.seen.Add(seenKey(it, post))
+	}
+	return nil
+}
+
+// seenKey - ключ сохранённой новости в множестве недавних.
+func seenKey(it *item, post storage.Post) string {
+	return it.src.URL + " " + post.CanonicalURL
+}
+
+// recentSet - множество последних добавленных строк ограниченного размера.
+// При переполнении забываются самые старые.
+type recentSet struct {
+	mu    sync.Mutex
+	items map[string]struct{}
+	order []string
+	next  int
+}
+
+func newRecentSet(size int) *recentSet {
+	return &recentSet{
+		items: make(map[string]struct{}, size),
+		order: make([]string, size),
+	}
+}
+
+func (s *recentSet) Has(v string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.items[v]
+	return ok
+}
+
+func (s *recentSet) Add(v string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[v]; ok {
+		return
+	}
+	if old := s.order[s.next]; old != "" {
+		delete(s.items, old)
+	}
+	s.order[s.next] = v
+	s.items[v] = struct{}{}
+	s.next = (s.next + 1) % len(s.order)
+}
@@ -0,0 +1,130 @@
+// Пакет opml читает и записывает списки лент в формате OPML 2.0,
+// которым обмениваются RSS-ридеры.
+package opml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+// ErrInvalid - документ не является OPML.
+var ErrInvalid = errors.New("некорректный OPML")
+
+// Разделитель вложенных папок в категории ленты.
+const categorySep = "/"
+
+type document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    head     `xml:"head"`
+	Body    body     `xml:"body"`
+}
+
+type head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type body struct {
+	Outlines []outline `xml:"outline"`
+}
+
+type outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Category string    `xml:"category,attr,omitempty"`
+	Outlines []outline `xml:"outline"`
+}
+
+// Parse читает ленты из OPML. Папки, в которые вложена лента,
+// становятся её категорией: "Go/Блоги". Если лента не вложена в папку,
+// используется её атрибут category.
+func Parse(r io.Reader) ([]storage.Source, error) {
+	var doc document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	var sources []storage.Source
+	var walk func(outlines []outline, folders []string)
+	walk = func(outlines []outline, folders []string) {
+		for _, o := range outlines {
+			name := strings.TrimSpace(o.Title)
+			if name == "" {
+				name = strings.TrimSpace(o.Text)
+			}
+			if o.XMLURL == "" {
+				walk(o.Outlines, append(folders[:len(folders):len(folders)], name))
+				continue
+			}
+			category := strings.Join(folders, categorySep)
+			if category == "" {
+				category = strings.Trim(strings.TrimSpace(o.Category), categorySep)
+			}
+			sources = append(sources, storage.Source{
+				Name:     name,
+				URL:      strings.TrimSpace(o.XMLURL),
+				Link:     strings.TrimSpace(o.HTMLURL),
+				Category: category,
+			})
+		}
+	}
+	walk(doc.Body.Outlines, nil)
+	return sources, nil
+}
+
+// Write записывает ленты в OPML. Ленты с категорией помещаются
+// в папки, вложенные папки создаются по частям категории.
+func Write(w io.Writer, title string, sources []storage.Source) error {
+	doc := document{
+		Version: "2.0",
+		Head: head{
+			Title:       title,
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+	sorted := append([]storage.Source(nil), sources...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Category < sorted[j].Category })
+	for _, src := range sorted {
+		list := &doc.Body.Outlines
+		if src.Category != "" {
+			for _, folder := range strings.Split(src.Category, categorySep) {
+				list = &folderOutline(list, folder).Outlines
+			}
+		}
+		*list = append(*list, outline{
+			Text:    src.Name,
+			Title:   src.Name,
+			Type:    "rss",
+			XMLURL:  src.URL,
+			HTMLURL: src.Link,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+// folderOutline возвращает папку name из списка, добавляя её при необходимости.
+func folderOutline(list *[]outline, name string) *outline {
+	for i := range *list {
+		if o := &(*list)[i]; o.XMLURL == "" && o.Text == name {
+			return o
+		}
+	}
+	*list = append(*list, outline{Text: name, Title: name})
+	return &(*list)[len(*list)-1]
+}
@@ -0,0 +1,65 @@
+package opml
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+var wantSources = []storage.Source{
+	{Name: "The Go Blog", URL: "https://go.dev/blog/feed.atom", Link: "https://go.dev/blog", Category: "Go/Блоги"},
+	{Name: "Golang Weekly", URL: "https://cprss.s3.amazonaws.com/golangweekly.com.xml", Category: "Go"},
+	{Name: "Хабр: Go", URL: "https://habr.com/ru/rss/hub/go/all/?fl=ru", Category: "Новости"},
+	{Name: "Без категории", URL: "https://example.com/rss"},
+}
+
+func TestParse(t *testing.T) {
+	f, err := os.Open("testdata/feeds.opml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, wantSources) {
+		t.Errorf("Parse() =\n%+v\nожидалось\n%+v", got, wantSources)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "GoNews", wantSources); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// при записи ленты группируются по категориям
+	byURL := map[string]storage.Source{}
+	for _, src := range got {
+		byURL[src.URL] = src
+	}
+	if len(got) != len(wantSources) {
+		t.Fatalf("прочитано %d лент, ожидалось %d", len(got), len(wantSources))
+	}
+	for _, want := range wantSources {
+		if !reflect.DeepEqual(byURL[want.URL], want) {
+			t.Errorf("после записи и чтения %+v, ожидалось %+v", byURL[want.URL], want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse(strings.NewReader("<html><body>не OPML"))
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("Parse() = %v, ожидалась ErrInvalid", err)
+	}
+}
@@ -0,0 +1,199 @@
+// Пакет retention удаляет устаревшие новости и комментарии к ним.
+//
+// Новость устаревает через Days дней после публикации, если у неё нет
+// комментариев или KeepCommented выключен. Есть ли комментарии, решает
+// БД комментариев, а не счётчик в новости. Устаревшие новости удаляются
+// пачками по BatchSize, чтобы не блокировать таблицу надолго, а при
+// Archive сначала копируются в архив. Затем удаляются комментарии
+// к удалённым новостям; комментарии к другим новостям не трогаются,
+// даже если самих новостей в БД нет.
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Config - политика хранения новостей.
+type Config struct {
+	// Days - сколько дней хранить новости; 0 - хранить всегда.
+	Days int `json:"days"`
+	// KeepCommented - не удалять новости с комментариями.
+	KeepCommented bool `json:"keep_commented"`
+	// Archive - переносить удаляемые строки в архивные таблицы.
+	Archive bool `json:"archive"`
+	// BatchSize - сколько новостей удалять одним запросом.
+	BatchSize int `json:"batch_size"`
+	// Interval - период очистки, минуты.
+	Interval int `json:"interval"`
+}
+
+// DefaultConfig - параметры очистки по умолчанию. Срок хранения
+// по умолчанию не задан: очистка включается явно.
+var DefaultConfig = Config{
+	BatchSize: 500,
+	Interval:  60,
+}
+
+// withDefaults заменяет незаданные параметры значениями по умолчанию.
+func (c Config) withDefaults() Config {
+	if c.BatchSize < 1 {
+		c.BatchSize = DefaultConfig.BatchSize
+	}
+	if c.Interval < 1 {
+		c.Interval = DefaultConfig.Interval
+	}
+	return c
+}
+
+// NewsStore - хранилище новостей.
+type NewsStore interface {
+	// ExpiredNews возвращает до limit id новостей, опубликованных раньше
+	// before, больших after, по возрастанию.
+	ExpiredNews(ctx context.Context, before int64, after, limit int) ([]int, error)
+	// RemoveNews удаляет новости, при archive - с переносом в архив,
+	// и возвращает число удалённых.
+	RemoveNews(ctx context.Context, ids []int, archive bool) (int, error)
+}
+
+// CommentStore - хранилище комментариев.
+type CommentStore interface {
+	// CommentCounts возвращает число комментариев к каждой новости.
+	CommentCounts(ctx context.Context) (map[int]int, error)
+	// RemoveByNews удаляет комментарии к новостям, при archive -
+	// с переносом в архив, и возвращает число удалённых.
+	RemoveByNews(ctx context.Context, newsIDs []int, archive bool) (int, error)
+}
+
+// Report - итог очистки.
+type Report struct {
+	// News - число удалённых новостей.
+	News int `json:"news"`
+	// Comments - число удалённых комментариев.
+	Comments int `json:"comments"`
+	// Before - новости, опубликованные раньше этого времени, устарели.
+	Before time.Time `json:"before"`
+	// DryRun - ничего не удалено, числа показывают, что было бы удалено.
+	DryRun bool `json:"dry_run"`
+}
+
+// Job - задача очистки.
+type Job struct {
+	cfg      Config
+	news     NewsStore
+	comments CommentStore
+
+	// Now - источник времени, в тестах подменяется.
+	Now func() time.Time
+}
+
+// New создаёт задачу очистки. comments может быть nil,
+// тогда комментарии не очищаются.
+func New(cfg Config, news NewsStore, comments CommentStore) *Job {
+	return &Job{
+		cfg:      cfg.withDefaults(),
+		news:     news,
+		comments: comments,
+		Now:      time.Now,
+	}
+}
+
+// Run выполняет очистку раз в Interval минут, пока не будет отменён
+// контекст. Первая очистка - через Interval после запуска, когда
+// новости из лент уже загружены. Если срок хранения не задан,
+// сразу возвращается.
+func (j *Job) Run(ctx context.Context) {
+	if j.cfg.Days < 1 {
+		return
+	}
+	t := time.NewTicker(time.Duration(j.cfg.Interval) * time.Minute)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		r, err := j.Prune(ctx, false)
+		if err != nil {
+			log.Println("очистка:", err)
+		} else if r.News > 0 || r.Comments > 0 {
+			log.Printf("очистка: удалено новостей %d, комментариев %d", r.News, r.Comments)
+		}
+	}
+}
+
+// Prune удаляет устаревшие новости и комментарии к ним.
+// При dryRun ничего не удаляет, а только подсчитывает.
+func (j *Job) Prune(ctx context.Context, dryRun bool) (Report, error) {
+	r := Report{DryRun: dryRun}
+	if j.cfg.Days < 1 {
+		return r, nil
+	}
+	r.Before = j.Now().AddDate(0, 0, -j.cfg.Days)
+	var counts map[int]int
+	if j.comments != nil {
+		var err error
+		counts, err = j.comments.CommentCounts(ctx)
+		if err != nil {
+			return r, err
+		}
+	}
+
+	// комментарии удаляются только к новостям, удалённым здесь же
+	var removed []int
+	after := 0
+	for {
+		ids, err := j.news.ExpiredNews(ctx, r.Before.Unix(), after, j.cfg.BatchSize)
+		if err != nil {
+			return r, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		after = ids[len(ids)-1]
+		if j.cfg.KeepCommented {
+			ids = withoutComments(ids, counts)
+			if len(ids) == 0 {
+				continue
+			}
+		}
+		removed = append(removed, ids...)
+		if dryRun {
+			r.News += len(ids)
+			continue
+		}
+		n, err := j.news.RemoveNews(ctx, ids, j.cfg.Archive)
+		r.News += n
+		if err != nil {
+			return r, err
+		}
+	}
+
+	var commented []int
+	for _, id := range removed {
+		if counts[id] > 0 {
+			commented = append(commented, id)
+			r.Comments += counts[id]
+		}
+	}
+	if dryRun || len(commented) == 0 {
+		return r, nil
+	}
+	var err error
+	r.Comments, err = j.comments.RemoveByNews(ctx, commented, j.cfg.Archive)
+	return r, err
+}
+
+// withoutComments возвращает те из ids, к новостям с которыми нет
+// комментариев.
+func withoutComments(ids []int, counts map[int]int) []int {
+	var out []int
+	for _, id := range ids {
+		if counts[id] == 0 {
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,149 @@
+package retention
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Хранилище новостей в памяти: время публикации новости.
+type memNews struct {
+	posts    map[int]int64
+	archived []int
+	batches  int
+}
+
+func (m *memNews) ExpiredNews(ctx context.Context, before int64, after, limit int) ([]int, error) {
+	var ids []int
+	for id, pubTime := range m.posts {
+		if pubTime < before && id > after {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+func (m *memNews) RemoveNews(ctx context.Context, ids []int, archive bool) (int, error) {
+	m.batches++
+	for _, id := range ids {
+		if archive {
+			m.archived = append(m.archived, id)
+		}
+		delete(m.posts, id)
+	}
+	return len(ids), nil
+}
+
+// Хранилище комментариев в памяти: число комментариев к новости.
+type memComments map[int]int
+
+func (m memComments) CommentCounts(ctx context.Context) (map[int]int, error) {
+	return m, nil
+}
+
+func (m memComments) RemoveByNews(ctx context.Context, newsIDs []int, archive bool) (int, error) {
+	n := 0
+	for _, id := range newsIDs {
+		n += m[id]
+		delete(m, id)
+	}
+	return n, nil
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	old := now.AddDate(0, 0, -40).Unix()
+	fresh := now.AddDate(0, 0, -5).Unix()
+	newStores := func() (*memNews, memComments) {
+		news := &memNews{posts: map[int]int64{1: old, 2: old, 3: old, 4: fresh, 5: old}}
+		// новости 9 в БД нет, но её комментарии не удаляются
+		comments := memComments{2: 2, 4: 1, 9: 3}
+		return news, comments
+	}
+
+	tests := []struct {
+		name          string
+		keepCommented bool
+		wantNews      int
+		wantComments  int
+		wantLeft      []int
+	}{
+		{"новости с комментариями остаются", true, 3, 0, []int{2, 4}},
+		{"удаляются и новости с комментариями", false, 4, 2, []int{4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Days: 30, KeepCommented: tt.keepCommented, Archive: true, BatchSize: 2}
+
+			// пробный запуск ничего не удаляет
+			news, comments := newStores()
+			job := New(cfg, news, comments)
+			job.Now = func() time.Time { return now }
+			r, err := job.Prune(context.Background(), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.News != tt.wantNews || r.Comments != tt.wantComments || !r.DryRun {
+				t.Errorf("пробный запуск: %+v, ожидалось новостей %d, комментариев %d", r, tt.wantNews, tt.wantComments)
+			}
+			if len(news.posts) != 5 || len(comments) != 3 {
+				t.Errorf("пробный запуск удалил данные: новостей %d, комментариев к %d новостям", len(news.posts), len(comments))
+			}
+
+			r, err = job.Prune(context.Background(), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.News != tt.wantNews || r.Comments != tt.wantComments || r.DryRun {
+				t.Errorf("очистка: %+v, ожидалось новостей %d, комментариев %d", r, tt.wantNews, tt.wantComments)
+			}
+			if comments[9] != 3 {
+				t.Errorf("удалены комментарии к новости, которой нет в БД")
+			}
+			var left []int
+			for id := range news.posts {
+				left = append(left, id)
+			}
+			sort.Ints(left)
+			if !reflect.DeepEqual(left, tt.wantLeft) {
+				t.Errorf("остались новости %v, ожидались %v", left, tt.wantLeft)
+			}
+			if len(news.archived) != tt.wantNews {
+				t.Errorf("в архиве %d новостей, ожидалось %d", len(news.archived), tt.wantNews)
+			}
+			if want := (tt.wantNews + cfg.BatchSize - 1) / cfg.BatchSize; news.batches != want {
+				t.Errorf("удалено за %d запросов, ожидалось %d", news.batches, want)
+			}
+		})
+	}
+}
+
+func TestPruneDisabled(t *testing.T) {
+	news := &memNews{posts: map[int]int64{1: 1}}
+	r, err := New(Config{}, news, nil).Prune(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.News != 0 || len(news.posts) != 1 {
+		t.Errorf("без срока хранения удалено %d новостей", r.News)
+	}
+}
+
+func TestPruneEmptyNews(t *testing.T) {
+	// новости ещё не загружены: комментарии к ним не удаляются
+	news := &memNews{posts: map[int]int64{}}
+	comments := memComments{1: 2, 2: 1}
+	r, err := New(Config{Days: 30}, news, comments).Prune(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Comments != 0 || len(comments) != 2 {
+		t.Errorf("удалено комментариев %d при пустой БД новостей", r.Comments)
+	}
+}
@@ -0,0 +1,52 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+
+	"golang.org/x/net/html/charset"
+)
+
+// ErrCharset - кодировка ленты не поддерживается.
+var ErrCharset = errors.New("неизвестная кодировка")
+
+// charsetReader перекодирует ленту в UTF-8 по кодировке из объявления
+// XML: windows-1251, koi8-r и другие кодировки из стандарта WHATWG.
+// Используется как xml.Decoder.CharsetReader.
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	enc, name := charset.Lookup(label)
+	if enc == nil {
+		return nil, fmt.Errorf("%w: %s", ErrCharset, label)
+	}
+	if name == "utf-8" {
+		return input, nil
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// Атрибут encoding в объявлении XML.
+var xmlEncoding = regexp.MustCompile(`^(\xef\xbb\xbf)?(\s*<\?xml[^>]*?encoding\s*=\s*["'])[^"']*(["'])`)
+
+// toUTF8 перекодирует тело ответа в UTF-8 по кодировке из заголовка
+// Content-Type. Кодировка из заголовка главнее объявленной в XML
+// (RFC 7303), поэтому объявление заменяется на UTF-8. Если кодировка
+// в заголовке не указана, тело не меняется.
+func toUTF8(b []byte, contentType string) ([]byte, error) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["charset"] == "" {
+		return b, nil
+	}
+	enc, name := charset.Lookup(params["charset"])
+	if enc == nil {
+		return nil, fmt.Errorf("%w: %s", ErrCharset, params["charset"])
+	}
+	if name != "utf-8" {
+		if b, err = enc.NewDecoder().Bytes(b); err != nil {
+			return nil, err
+		}
+	}
+	return xmlEncoding.ReplaceAll(b, []byte("${2}UTF-8${3}")), nil
+}
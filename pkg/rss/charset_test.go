@@ -0,0 +1,84 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+const (
+	wantTitle   = "Съешь ещё этих мягких французских булок"
+	wantContent = "Да выпей же чаю. Ёлка, щука, эхо."
+)
+
+func TestParseBytesCharset(t *testing.T) {
+	for _, enc := range []string{"windows-1251", "koi8-r"} {
+		t.Run(enc, func(t *testing.T) {
+			b, err := os.ReadFile("testdata/" + enc + ".xml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			src, posts, err := ParseBytes(b, "https://example.ru/rss")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if src.Name != "Новости в кодировке "+enc {
+				t.Errorf("название ленты %q", src.Name)
+			}
+			if len(posts) != 1 || posts[0].Title != wantTitle || posts[0].Content != wantContent {
+				t.Errorf("новости раскодированы неверно: %+v", posts)
+			}
+		})
+	}
+}
+
+func TestParseBytesUnknownCharset(t *testing.T) {
+	b := []byte(`<?xml version="1.0" encoding="x-unknown"?><rss><channel></channel></rss>`)
+	if _, _, err := ParseBytes(b, "https://example.ru/rss"); !errors.Is(err, ErrCharset) {
+		t.Fatalf("ошибка %v, ожидалась ErrCharset", err)
+	}
+}
+
+// Кодировка из заголовка Content-Type главнее объявленной в XML.
+func TestFetchCharset(t *testing.T) {
+	koi8, err := os.ReadFile("testdata/koi8-r.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+	}{
+		{"кодировка из XML", "application/rss+xml", koi8},
+		{"кодировка из заголовка", "text/xml; charset=KOI8-R", koi8},
+		// сервер отдаёт koi8-r, а в объявлении XML осталась windows-1251
+		{"заголовок главнее XML", "text/xml; charset=koi8-r",
+			bytes.Replace(koi8, []byte(`encoding="koi8-r"`), []byte(`encoding="windows-1251"`), 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write(tt.body)
+			}))
+			defer srv.Close()
+
+			b, err := Fetch(context.Background(), srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, posts, err := ParseBytes(b, srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(posts) != 1 || posts[0].Title != wantTitle {
+				t.Errorf("новости раскодированы неверно: %+v", posts)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package rss
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrDate - дату публикации не удалось разобрать.
+var ErrDate = errors.New("неизвестный формат даты")
+
+// Форматы ISO 8601 и RFC 3339, в том числе без пояса и секунд.
+// Дата без пояса считается датой UTC.
+var isoLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+var months = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March,
+	"apr": time.April, "may": time.May, "jun": time.June,
+	"jul": time.July, "aug": time.August, "sep": time.September,
+	"oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+var weekdays = map[string]bool{
+	"mon": true, "tue": true, "wed": true, "thu": true, "fri": true, "sat": true, "sun": true,
+}
+
+// Смещения названных поясов в часах. Неизвестный пояс считается UTC.
+var zones = map[string]int{
+	"UT": 0, "UTC": 0, "GMT": 0, "Z": 0,
+	"EST": -5, "EDT": -4, "CST": -6, "CDT": -5,
+	"MST": -7, "MDT": -6, "PST": -8, "PDT": -7,
+	"WET": 0, "WEST": 1, "CET": 1, "CEST": 2, "EET": 2, "EEST": 3,
+	"MSK": 3, "MSD": 4, "SAMT": 4, "YEKT": 5, "OMST": 6, "KRAT": 7,
+	"IRKT": 8, "YAKT": 9, "VLAT": 10, "MAGT": 11, "PETT": 12,
+}
+
+// ParseDate разбирает дату публикации из ленты: RFC 822, RFC 1123,
+// RFC 3339 и их варианты из реальных лент - без дня недели и секунд,
+// с двузначным годом, однозначным днём, полными названиями месяцев
+// и поясами вида MSK, EST или +03:00.
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, ErrDate
+	}
+	for _, layout := range isoLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return parseRFC822(s)
+}
+
+// parseRFC822 разбирает дату вида "[Mon,] 2 Jan [20]06 15:04[:05] [zone]".
+func parseRFC822(s string) (time.Time, error) {
+	f := strings.Fields(strings.ReplaceAll(s, ",", " "))
+	if len(f) > 0 && weekdays[prefix(f[0])] {
+		f = f[1:]
+	}
+	if len(f) < 4 || len(f) > 5 {
+		return time.Time{}, ErrDate
+	}
+
+	day, err := strconv.Atoi(f[0])
+	if err != nil {
+		return time.Time{}, ErrDate
+	}
+	month, ok := months[prefix(f[1])]
+	if !ok {
+		return time.Time{}, ErrDate
+	}
+	year, err := strconv.Atoi(f[2])
+	if err != nil {
+		return time.Time{}, ErrDate
+	}
+	// двузначный год по RFC 2822: 00-49 - 2000-е, 50-99 - 1900-е
+	if len(f[2]) == 2 {
+		if year < 50 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+	hour, min, sec, err := parseClock(f[3])
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc := time.UTC
+	if len(f) == 5 {
+		loc, err = parseZone(f[4])
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	t := time.Date(year, month, day, hour, min, sec, 0, loc)
+	// time.Date переносит 31 апреля на 1 мая
+	if t.Day() != day {
+		return time.Time{}, ErrDate
+	}
+	return t, nil
+}
+
+// prefix возвращает первые три буквы слова в нижнем регистре.
+func prefix(s string) string {
+	s = strings.ToLower(s)
+	if len(s) > 3 {
+		s = s[:3]
+	}
+	return s
+}
+
+// parseClock разбирает время "15:04:05" или "15:04".
+func parseClock(s string) (hour, min, sec int, err error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, 0, 0, ErrDate
+	}
+	var v [3]int
+	for i, p := range parts {
+		if v[i], err = strconv.Atoi(p); err != nil {
+			return 0, 0, 0, ErrDate
+		}
+	}
+	if v[0] > 23 || v[1] > 59 || v[2] > 60 {
+		return 0, 0, 0, ErrDate
+	}
+	return v[0], v[1], v[2], nil
+}
+
+// parseZone разбирает пояс: "+0300", "-07:00" или название.
+func parseZone(s string) (*time.Location, error) {
+	if s[0] == '+' || s[0] == '-' {
+		digits := strings.ReplaceAll(s[1:], ":", "")
+		if len(digits) != 4 {
+			return nil, ErrDate
+		}
+		hh, err1 := strconv.Atoi(digits[:2])
+		mm, err2 := strconv.Atoi(digits[2:])
+		if err1 != nil || err2 != nil || hh > 14 || mm > 59 {
+			return nil, ErrDate
+		}
+		offset := hh*3600 + mm*60
+		if s[0] == '-' {
+			offset = -offset
+		}
+		return time.FixedZone("", offset), nil
+	}
+	name := strings.ToUpper(s)
+	hours, ok := zones[name]
+	if !ok {
+		return time.UTC, nil
+	}
+	return time.FixedZone(name, hours*3600), nil
+}
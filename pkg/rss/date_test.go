@@ -0,0 +1,69 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*3600)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		// RFC 1123 и RFC 822
+		{"Mon, 02 Jan 2006 15:04:05 -0700", time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 GMT", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"Mon, 2 Jan 2006 15:04:05 +0300", time.Date(2006, 1, 2, 12, 4, 5, 0, time.UTC)},
+		{"2 Jan 2006 15:04:05 +0000", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 06 15:04:05 +0000", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"Sat, 01 Jan 99 00:00:00 GMT", time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04 +0000", time.Date(2006, 1, 2, 15, 4, 0, 0, time.UTC)},
+		{"Monday, 2 January 2006 15:04:05 +0000", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"  Mon,  2 Jan 2006  15:04:05  +0000 ", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 +03:00", time.Date(2006, 1, 2, 12, 4, 5, 0, time.UTC)},
+		// названные пояса
+		{"Mon, 02 Jan 2006 15:04:05 MSK", time.Date(2006, 1, 2, 15, 4, 5, 0, msk)},
+		{"Mon, 02 Jan 2006 15:04:05 EST", time.Date(2006, 1, 2, 20, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 PDT", time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 UT", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 XYZ", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		// RFC 3339 и ISO 8601
+		{"2006-01-02T15:04:05Z", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2006-01-02T15:04:05+03:00", time.Date(2006, 1, 2, 12, 4, 5, 0, time.UTC)},
+		{"2006-01-02T15:04:05.123Z", time.Date(2006, 1, 2, 15, 4, 5, 123e6, time.UTC)},
+		{"2006-01-02T15:04:05+0300", time.Date(2006, 1, 2, 12, 4, 5, 0, time.UTC)},
+		{"2006-01-02T15:04Z", time.Date(2006, 1, 2, 15, 4, 0, 0, time.UTC)},
+		{"2006-01-02T15:04:05", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2006-01-02 15:04:05", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2006-01-02", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.in)
+		if err != nil {
+			t.Errorf("ParseDate(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %v, ожидалось %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseDateInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"вчера",
+		"Mon, 31 Apr 2006 15:04:05 GMT",
+		"Mon, 02 Foo 2006 15:04:05 GMT",
+		"Mon, 02 Jan 2006 25:04:05 GMT",
+		"Mon, 02 Jan 2006 15:04:05 +30000",
+		"Mon, 02 Jan 2006",
+		"2006-13-02T15:04:05Z",
+	} {
+		if got, err := ParseDate(in); err == nil {
+			t.Errorf("ParseDate(%q) = %v, ожидалась ошибка", in, got)
+		}
+	}
+}
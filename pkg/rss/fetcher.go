@@ -0,0 +1,191 @@
+package rss
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ошибки загрузки ленты.
+var (
+	ErrBodyTooLarge     = errors.New("лента больше допустимого размера")
+	ErrTooManyRedirects = errors.New("слишком много перенаправлений")
+)
+
+// StatusError - сервер ленты ответил статусом, отличным от 200.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	// RetryAfter - пауза из заголовка Retry-After, если он был.
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: сервер ответил статусом %d", e.URL, e.StatusCode)
+}
+
+// Temporary сообщает, что запрос имеет смысл повторить позже:
+// сервер перегружен или недоступен.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// FetcherConfig - настройки загрузки лент.
+type FetcherConfig struct {
+	// Timeout - предельное время загрузки ленты, секунды.
+	Timeout int `json:"timeout"`
+	// MaxBodySize - наибольший размер ленты после распаковки, байты.
+	MaxBodySize int64  `json:"max_body_size"`
+	UserAgent   string `json:"user_agent"`
+	// MaxRedirects - наибольшее число перенаправлений.
+	MaxRedirects int `json:"max_redirects"`
+	// Feeds - заголовки и учётные данные отдельных лент по адресу ленты.
+	Feeds map[string]FeedOptions `json:"feeds"`
+}
+
+// FeedOptions - настройки запроса одной ленты.
+type FeedOptions struct {
+	Headers map[string]string `json:"headers"`
+	// Username и Password - учётные данные Basic-авторизации.
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// DefaultFetcherConfig - настройки загрузки по умолчанию.
+var DefaultFetcherConfig = FetcherConfig{
+	Timeout:      30,
+	MaxBodySize:  10 << 20,
+	UserAgent:    "GoNews/1.0 (RSS aggregator)",
+	MaxRedirects: 5,
+}
+
+// withDefaults заменяет незаданные параметры значениями по умолчанию.
+func (c FetcherConfig) withDefaults() FetcherConfig {
+	if c.Timeout < 1 {
+		c.Timeout = DefaultFetcherConfig.Timeout
+	}
+	if c.MaxBodySize < 1 {
+		c.MaxBodySize = DefaultFetcherConfig.MaxBodySize
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = DefaultFetcherConfig.UserAgent
+	}
+	if c.MaxRedirects < 1 {
+		c.MaxRedirects = DefaultFetcherConfig.MaxRedirects
+	}
+	return c
+}
+
+// Fetcher загружает ленты по HTTP.
+type Fetcher struct {
+	cfg    FetcherConfig
+	client *http.Client
+}
+
+// NewFetcher создаёт загрузчик лент.
+func NewFetcher(cfg FetcherConfig) *Fetcher {
+	cfg = cfg.withDefaults()
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: timeout,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConnsPerHost:   4,
+		// gzip распаковывается в Fetch, чтобы ограничение размера
+		// действовало на распакованную ленту
+		DisableCompression: true,
+	}
+	maxRedirects := cfg.MaxRedirects
+	return &Fetcher{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > maxRedirects {
+					return ErrTooManyRedirects
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// Fetch загружает ленту по адресу url. Если в заголовке Content-Type
+// указана кодировка, лента перекодируется в UTF-8. Ответ со статусом,
+// отличным от 200, возвращается как *StatusError.
+func (f *Fetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.cfg.UserAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.9, */*;q=0.8")
+	req.Header.Set("Accept-Encoding", "gzip")
+	if opts, ok := f.cfg.Feeds[url]; ok {
+		for k, v := range opts.Headers {
+			req.Header.Set(k, v)
+		}
+		if opts.Username != "" {
+			req.SetBasicAuth(opts.Username, opts.Password)
+		}
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// остаток тела вычитывается, чтобы соединение вернулось в пул
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil, &StatusError{
+			URL:        url,
+			StatusCode: resp.StatusCode,
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	body := io.Reader(resp.Body)
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+	b, err := io.ReadAll(io.LimitReader(body, f.cfg.MaxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > f.cfg.MaxBodySize {
+		return nil, fmt.Errorf("%w: %s", ErrBodyTooLarge, url)
+	}
+	return toUTF8(b, resp.Header.Get("Content-Type"))
+}
+
+// retryAfter разбирает заголовок Retry-After: число секунд или дату.
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
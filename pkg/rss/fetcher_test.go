@@ -0,0 +1,137 @@
+package rss
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFetcher(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rss", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("User-Agent")))
+	})
+	mux.HandleFunc("/gzip", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("Accept-Encoding = %q", r.Header.Get("Accept-Encoding"))
+		}
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte("<rss/>"))
+		gz.Close()
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(buf.Bytes())
+	})
+	mux.HandleFunc("/bomb", func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(bytes.Repeat([]byte{' '}, 1<<20))
+		gz.Close()
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(buf.Bytes())
+	})
+	mux.HandleFunc("/huge", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte{' '}, 2048))
+	})
+	mux.HandleFunc("/auth", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		w.Write([]byte(user + ":" + pass + ":" + r.Header.Get("X-Token")))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/rss", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := NewFetcher(FetcherConfig{
+		MaxBodySize: 1024,
+		UserAgent:   "test-agent",
+		Feeds: map[string]FeedOptions{
+			srv.URL + "/auth": {
+				Headers:  map[string]string{"X-Token": "t"},
+				Username: "user",
+				Password: "pass",
+			},
+		},
+	})
+	tests := []struct {
+		path string
+		want string
+		err  error
+	}{
+		{"/rss", "test-agent", nil},
+		{"/gzip", "<rss/>", nil},
+		{"/auth", "user:pass:t", nil},
+		{"/moved", "test-agent", nil},
+		{"/huge", "", ErrBodyTooLarge},
+		{"/bomb", "", ErrBodyTooLarge},
+		{"/loop", "", ErrTooManyRedirects},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			b, err := f.Fetch(context.Background(), srv.URL+tt.path)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ошибка %v, ожидалась %v", err, tt.err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("получено %q, ожидалось %q", b, tt.want)
+			}
+		})
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		f := NewFetcher(FetcherConfig{})
+		f.client.Timeout = 50 * time.Millisecond
+		_, err := f.Fetch(context.Background(), srv.URL+"/slow")
+		var ne net.Error
+		if !errors.As(err, &ne) || !ne.Timeout() {
+			t.Fatalf("ошибка %v, ожидался тайм-аут", err)
+		}
+	})
+}
+
+func TestFetcherStatus(t *testing.T) {
+	tests := []struct {
+		status     int
+		retryAfter string
+		temporary  bool
+		wait       time.Duration
+	}{
+		{http.StatusNotFound, "", false, 0},
+		{http.StatusForbidden, "", false, 0},
+		{http.StatusTooManyRequests, "120", true, 2 * time.Minute},
+		{http.StatusServiceUnavailable, "", true, 0},
+		{http.StatusNotModified, "", false, 0},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.retryAfter != "" {
+				w.Header().Set("Retry-After", tt.retryAfter)
+			}
+			w.WriteHeader(tt.status)
+		}))
+		_, err := NewFetcher(FetcherConfig{}).Fetch(context.Background(), srv.URL)
+		srv.Close()
+
+		var se *StatusError
+		if !errors.As(err, &se) {
+			t.Errorf("%d: ошибка %v, ожидалась *StatusError", tt.status, err)
+			continue
+		}
+		if se.StatusCode != tt.status || se.Temporary() != tt.temporary || se.RetryAfter != tt.wait {
+			t.Errorf("%d: %+v, Temporary() = %v", tt.status, se, se.Temporary())
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+// Тесты разбора работают без сети: ленты из testdata отдаёт тестовый
+// сервер, результат сравнивается с эталоном из testdata/golden.
+// После изменения разбора эталоны обновляются командой
+//
+//	go test ./pkg/rss -run TestFixtures -update
+//
+// Ленты, записанные с реальных сайтов, загружаются заново только
+// по явному запросу:
+//
+//	go test ./pkg/rss -run TestFixtures -record -update
+var (
+	update = flag.Bool("update", false, "перезаписать эталоны в testdata/golden")
+	record = flag.Bool("record", false, "заново загрузить записанные ленты из сети")
+)
+
+// Лента, которую отдаёт тестовый сервер.
+type fixture struct {
+	name        string // имя эталона и путь на тестовом сервере
+	file        string
+	contentType string
+	// url - адрес, с которого записана лента, пусто для искусственных лент
+	url string
+}
+
+var fixtures = []fixture{
+	{"habr", "habr.xml", "application/rss+xml; charset=utf-8", "https://habr.com/ru/rss/best/daily/?fl=ru"},
+	{"windows-1251", "windows-1251.xml", "text/xml", ""},
+	{"koi8-r", "koi8-r.xml", "application/rss+xml", ""},
+	// сервер отдаёт windows-1251 и сообщает об этом только в заголовке
+	{"charset-from-header", "mislabeled-1251.xml", "text/xml; charset=windows-1251", ""},
+	// та же лента без кодировки в заголовке не раскодируется
+	{"wrong-charset", "mislabeled-1251.xml", "text/xml", ""},
+	// Atom не поддерживается: эталон фиксирует ошибку разбора
+	{"atom", "atom.xml", "application/atom+xml", ""},
+	{"broken", "broken.xml", "application/rss+xml", ""},
+	{"not-a-feed", "not-a-feed.html", "text/html; charset=utf-8", ""},
+}
+
+// Результат разбора ленты, хранится в эталоне.
+type golden struct {
+	Source *storage.Source `json:"source,omitempty"`
+	Posts  []storage.Post  `json:"posts,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// fixtureServer отдаёт ленты из testdata по имени: /habr, /koi8-r...
+func fixtureServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	for _, f := range fixtures {
+		f := f
+		mux.HandleFunc("/"+f.name, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", f.contentType)
+			http.ServeFile(w, r, filepath.Join("testdata", f.file))
+		})
+	}
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFixtures(t *testing.T) {
+	if *record {
+		recordFixtures(t)
+	}
+	srv := fixtureServer(t)
+	for _, f := range fixtures {
+		t.Run(f.name, func(t *testing.T) {
+			var got golden
+			b, err := Fetch(context.Background(), srv.URL+"/"+f.name)
+			if err == nil {
+				// адрес тестового сервера меняется, в эталоне - постоянный
+				var src storage.Source
+				src, got.Posts, err = ParseBytes(b, "https://fixtures.test/"+f.name)
+				got.Source = &src
+			}
+			if err != nil {
+				got = golden{Error: err.Error()}
+			}
+			compareGolden(t, f.name, got)
+		})
+	}
+}
+
+// compareGolden сравнивает результат с эталоном или перезаписывает эталон.
+func compareGolden(t *testing.T, name string, got golden) {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(got); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	path := filepath.Join("testdata", "golden", name+".json")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("нет эталона, запустите тест с флагом -update: %v", err)
+	}
+	if !bytes.Equal(b, want) {
+		t.Errorf("результат разбора отличается от эталона %s:\n%s", path, b)
+	}
+}
+
+// recordFixtures заново загружает записанные ленты с их сайтов.
+func recordFixtures(t *testing.T) {
+	t.Helper()
+	for _, f := range fixtures {
+		if f.url == "" {
+			continue
+		}
+		resp, err := http.Get(f.url)
+		if err != nil {
+			t.Fatalf("%s: %v", f.url, err)
+		}
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", f.url, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: статус %d", f.url, resp.StatusCode)
+		}
+		if err := os.WriteFile(filepath.Join("testdata", f.file), b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("записана лента %s", f.url)
+	}
+}
+
+func TestParse(t *testing.T) {
+	srv := fixtureServer(t)
+	src, feed, err := Parse(srv.URL + "/habr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed) == 0 {
+		t.Fatal("данные не раскодированы")
+	}
+	if src.Name == "" {
+		t.Error("не раскодировано название ленты")
+	}
+}
+
+// hugeFeed возвращает ленту RSS из n новостей.
+func hugeFeed(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><rss version="2.0"><channel><title>Большая лента</title>`)
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, `<item><title>Новость %d</title><link>https://example.ru/%d</link>`+
+			`<description>%s</description><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item>`,
+			i, i, strings.Repeat("текст ", 30))
+	}
+	b.WriteString(`</channel></rss>`)
+	return b.Bytes()
+}
+
+func TestHugeFeed(t *testing.T) {
+	const n = 5000
+	body := hugeFeed(n)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	b, err := Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, posts, err := ParseBytes(b, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != n || posts[n-1].Link != fmt.Sprintf("https://example.ru/%d", n) {
+		t.Fatalf("раскодировано %d новостей из %d", len(posts), n)
+	}
+
+	// лента больше допустимого размера не загружается
+	f := NewFetcher(FetcherConfig{MaxBodySize: int64(len(body)) / 2})
+	if _, err := f.Fetch(context.Background(), srv.URL); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("ошибка %v, ожидалась ErrBodyTooLarge", err)
+	}
+}
@@ -2,15 +2,15 @@
 package rss
 
 import (
+	"bytes"
+	"context"
 	"encoding/xml"
-	"io/ioutil"
-	"net/http"
+	"html"
+	"regexp"
 	"strings"
-	"time"
 
+	"Skillfactory-APIGateway/pkg/sanitize"
 	"Skillfactory-APIGateway/pkg/storage"
-
-	strip "github.com/grokify/html-strip-tags-go"
 )
 
 type Feed struct {
@@ -26,45 +26,143 @@ type Channel struct {
 }
 
 type Item struct {
-	Title       string `xml:"title"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
-	Link        string `xml:"link"`
+	Title       string      `xml:"title"`
+	Description string      `xml:"description"`
+	Encoded     string      `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate     string      `xml:"pubDate"`
+	Link        string      `xml:"link"`
+	GUID        string      `xml:"guid"`
+	Author      string      `xml:"author"`
+	Creator     string      `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories  []string    `xml:"category"`
+	Enclosures  []Enclosure `xml:"enclosure"`
+	Media       []Media     `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnails  []Media     `xml:"http://search.yahoo.com/mrss/ thumbnail"`
 }
 
-// Parse читает rss-поток и возвращет
-// массив раскодированных новостей.
-func Parse(url string) ([]storage.Post, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	b, err := ioutil.ReadAll(resp.Body)
+// Enclosure - вложение новости (аудио, видео, картинка).
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int64  `xml:"length,attr"`
+}
+
+// Media - элемент media:content или media:thumbnail из Media RSS.
+type Media struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Medium string `xml:"medium,attr"`
+	Size   int64  `xml:"fileSize,attr"`
+}
+
+// Parse читает rss-поток и возвращет описание ленты
+// и массив раскодированных новостей.
+func Parse(url string) (storage.Source, []storage.Post, error) {
+	b, err := Fetch(context.Background(), url)
 	if err != nil {
-		return nil, err
+		return storage.Source{}, nil, err
 	}
+	return ParseBytes(b, url)
+}
+
+// Загрузчик с настройками по умолчанию.
+var defaultFetcher = NewFetcher(DefaultFetcherConfig)
+
+// Fetch загружает rss-поток по адресу url с настройками по умолчанию.
+func Fetch(ctx context.Context, url string) ([]byte, error) {
+	return defaultFetcher.Fetch(ctx, url)
+}
+
+// ParseBytes раскодирует rss-поток, загруженный с адреса url.
+// Поток в кодировке, отличной от UTF-8, перекодируется по объявлению XML.
+func ParseBytes(b []byte, url string) (storage.Source, []storage.Post, error) {
 	var f Feed
-	err = xml.Unmarshal(b, &f)
+	d := xml.NewDecoder(bytes.NewReader(b))
+	d.CharsetReader = charsetReader
+	err := d.Decode(&f)
 	if err != nil {
-		return nil, err
+		return storage.Source{}, nil, err
+	}
+	src := storage.Source{
+		Name: strings.TrimSpace(f.Chanel.Title),
+		URL:  url,
+		Link: strings.TrimSpace(f.Chanel.Link),
 	}
 	var data []storage.Post
 	for _, item := range f.Chanel.Items {
 		var p storage.Post
 		p.Title = item.Title
-		p.Content = item.Description
-		p.Content = strip.StripTags(p.Content)
+		// полный текст из content:encoded, если он есть
+		p.RawHTML = item.Description
+		if strings.TrimSpace(item.Encoded) != "" {
+			p.RawHTML = item.Encoded
+		}
+		p.Content = sanitize.Text(p.RawHTML)
 		p.Link = item.Link
-
-		item.PubDate = strings.ReplaceAll(item.PubDate, ",", "")
-		t, err := time.Parse("Mon 2 Jan 2006 15:04:05 -0700", item.PubDate)
-		if err != nil {
-			t, err = time.Parse("Mon 2 Jan 2006 15:04:05 GMT", item.PubDate)
+		p.GUID = strings.TrimSpace(item.GUID)
+		p.Author = strings.TrimSpace(item.Author)
+		if p.Author == "" {
+			p.Author = strings.TrimSpace(item.Creator)
+		}
+		for _, c := range item.Categories {
+			if c = strings.TrimSpace(c); c != "" {
+				p.Categories = append(p.Categories, c)
+			}
 		}
-		if err == nil {
+		p.Enclosures, p.Image = enclosures(item)
+
+		// неразобранная дата остаётся нулевой, конвейер заменит её
+		// временем загрузки
+		p.RawDate = item.PubDate
+		if t, err := ParseDate(item.PubDate); err == nil {
 			p.PubTime = t.Unix()
 		}
 		data = append(data, p)
 	}
-	return data, nil
+	return src, data, nil
 }
+
+// enclosures собирает вложения новости из enclosure и media:content
+// и выбирает картинку: media:thumbnail, первое вложение-картинку
+// или первый <img> в тексте.
+func enclosures(item Item) ([]storage.Enclosure, string) {
+	var list []storage.Enclosure
+	seen := make(map[string]bool)
+	add := func(url, typ string, length int64) {
+		url = strings.TrimSpace(url)
+		if url == "" || seen[url] {
+			return
+		}
+		seen[url] = true
+		list = append(list, storage.Enclosure{URL: url, Type: typ, Length: length})
+	}
+	for _, e := range item.Enclosures {
+		add(e.URL, e.Type, e.Length)
+	}
+	for _, m := range item.Media {
+		typ := m.Type
+		if typ == "" && m.Medium != "" {
+			typ = m.Medium + "/*"
+		}
+		add(m.URL, typ, m.Size)
+	}
+
+	var image string
+	for _, m := range item.Thumbnails {
+		if image = strings.TrimSpace(m.URL); image != "" {
+			return list, image
+		}
+	}
+	for _, e := range list {
+		if strings.HasPrefix(e.Type, "image/") {
+			return list, e.URL
+		}
+	}
+	if m := imgSrc.FindStringSubmatch(item.Encoded + item.Description); m != nil {
+		image = html.UnescapeString(m[1])
+	}
+	return list, image
+}
+
+// Атрибут src первого тега img.
+var imgSrc = regexp.MustCompile(`(?i)<img[^>]+src\s*=\s*["']([^"']+)["']`)
@@ -1,16 +1,75 @@
 package rss
 
 import (
+	"reflect"
+	"strings"
 	"testing"
+
+	"Skillfactory-APIGateway/pkg/storage"
 )
 
-func TestParse(t *testing.T) {
-	feed, err := Parse("https://habr.com/ru/rss/best/daily/?fl=ru")
+const itemXML = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:media="http://search.yahoo.com/mrss/">
+<channel>
+	<title>Лента</title>
+	<link>https://example.com/</link>
+	<item>
+		<title>Новость</title>
+		<link>https://example.com/1</link>
+		<guid isPermaLink="false">post-1</guid>
+		<dc:creator>Иван</dc:creator>
+		<category>Go</category>
+		<category> </category>
+		<category>Базы данных</category>
+		<description>Кратко</description>
+		<content:encoded><![CDATA[<p>Полный <b>текст</b></p><img src="https://example.com/inline.png">]]></content:encoded>
+		<enclosure url="https://example.com/a.mp3" type="audio/mpeg" length="1024"/>
+		<media:content url="https://example.com/v.mp4" medium="video"/>
+		<media:thumbnail url="https://example.com/thumb.jpg"/>
+	</item>
+	<item>
+		<title>Вторая</title>
+		<link>https://example.com/2</link>
+		<author>petr@example.com</author>
+		<description>&lt;p&gt;Текст &lt;img src="https://example.com/2.png"&gt;&lt;/p&gt;</description>
+	</item>
+</channel>
+</rss>`
+
+func TestParseBytesItem(t *testing.T) {
+	_, posts, err := ParseBytes([]byte(itemXML), "https://example.com/rss")
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(feed) == 0 {
-		t.Fatal("данные не рскодированы")
+	if len(posts) != 2 {
+		t.Fatalf("получено %d новостей, ожидалось 2", len(posts))
+	}
+
+	p := posts[0]
+	if p.GUID != "post-1" || p.Author != "Иван" {
+		t.Errorf("guid %q, автор %q", p.GUID, p.Author)
+	}
+	if !reflect.DeepEqual(p.Categories, []string{"Go", "Базы данных"}) {
+		t.Errorf("рубрики %q", p.Categories)
+	}
+	want := []storage.Enclosure{
+		{URL: "https://example.com/a.mp3", Type: "audio/mpeg", Length: 1024},
+		{URL: "https://example.com/v.mp4", Type: "video/*"},
+	}
+	if !reflect.DeepEqual(p.Enclosures, want) {
+		t.Errorf("вложения %+v", p.Enclosures)
+	}
+	if p.Image != "https://example.com/thumb.jpg" {
+		t.Errorf("картинка %q", p.Image)
+	}
+	if !strings.Contains(p.RawHTML, "<b>текст</b>") || p.Content != "Полный текст" {
+		t.Errorf("html %q, текст %q", p.RawHTML, p.Content)
+	}
+
+	p = posts[1]
+	if p.Author != "petr@example.com" || p.Image != "https://example.com/2.png" {
+		t.Errorf("автор %q, картинка %q", p.Author, p.Image)
 	}
-	t.Logf("получено %d новостей\n%+v", len(feed), feed)
 }
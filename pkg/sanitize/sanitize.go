@@ -0,0 +1,266 @@
+// Пакет sanitize очищает HTML новостей из RSS-лент. Остаётся только
+// разрешённая разметка, скрипты, стили и обработчики событий удаляются,
+// картинки загружаются через прокси.
+package sanitize
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Разрешённые теги и их атрибуты.
+var allowed = map[string][]string{
+	"p":          nil,
+	"br":         nil,
+	"a":          {"href", "title"},
+	"code":       nil,
+	"pre":        nil,
+	"ul":         nil,
+	"ol":         nil,
+	"li":         nil,
+	"img":        {"src", "alt", "title"},
+	"b":          nil,
+	"strong":     nil,
+	"i":          nil,
+	"em":         nil,
+	"blockquote": nil,
+}
+
+// Теги, которые удаляются вместе с содержимым.
+var dropped = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"iframe":   true,
+	"object":   true,
+	"embed":    true,
+	"noscript": true,
+	"template": true,
+	"svg":      true,
+	"math":     true,
+	"head":     true,
+	"title":    true,
+	"textarea": true,
+	"select":   true,
+}
+
+// Теги, после которых в тексте начинается новый абзац.
+var blocks = map[string]bool{
+	"p": true, "br": true, "div": true, "li": true, "pre": true, "blockquote": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"tr": true, "td": true, "th": true, "hr": true, "ul": true, "ol": true,
+}
+
+// Sanitizer очищает HTML по списку разрешённых тегов.
+type Sanitizer struct {
+	// ImageProxy - префикс адреса прокси картинок, к нему дописывается
+	// экранированный адрес картинки. Если пусто, адрес не меняется.
+	ImageProxy string
+}
+
+// HTML возвращает безопасную разметку. Относительные ссылки
+// приводятся к абсолютным относительно base, если он задан.
+func (s Sanitizer) HTML(src string, base *url.URL) string {
+	z := html.NewTokenizer(strings.NewReader(src))
+	var b strings.Builder
+	var open []string
+	skip := 0
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			for i := len(open) - 1; i >= 0; i-- {
+				b.WriteString("</" + open[i] + ">")
+			}
+			return strings.TrimSpace(b.String())
+		case html.TextToken:
+			if skip == 0 {
+				b.WriteString(html.EscapeString(string(z.Text())))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			if dropped[t.Data] {
+				if tt == html.StartTagToken {
+					skip++
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			attrs, ok := allowed[t.Data]
+			if !ok {
+				continue
+			}
+			tag, ok := s.startTag(t, attrs, base)
+			if !ok {
+				continue
+			}
+			// новый пункт списка или абзац закрывает предыдущий
+			if (t.Data == "li" || t.Data == "p") && len(open) > 0 && open[len(open)-1] == t.Data {
+				b.WriteString("</" + t.Data + ">")
+				open = open[:len(open)-1]
+			}
+			b.WriteString(tag)
+			if tt == html.StartTagToken && t.Data != "br" && t.Data != "img" {
+				open = append(open, t.Data)
+			}
+		case html.EndTagToken:
+			t := z.Token()
+			if dropped[t.Data] {
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			// закрываются и все вложенные теги, оставшиеся открытыми
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != t.Data {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		}
+	}
+}
+
+// startTag собирает открывающий тег с разрешёнными атрибутами.
+// Картинки без допустимого адреса отбрасываются.
+func (s Sanitizer) startTag(t html.Token, attrs []string, base *url.URL) (string, bool) {
+	var b strings.Builder
+	b.WriteString("<" + t.Data)
+	hasSrc := false
+	for _, a := range t.Attr {
+		if a.Namespace != "" || !contains(attrs, a.Key) {
+			continue
+		}
+		val := a.Val
+		switch a.Key {
+		case "href":
+			u := absURL(val, base, "http", "https", "mailto")
+			if u == "" {
+				continue
+			}
+			val = u
+		case "src":
+			u := absURL(val, base, "http", "https")
+			if u == "" {
+				continue
+			}
+			val = s.proxy(u)
+			hasSrc = true
+		}
+		b.WriteString(" " + a.Key + `="` + html.EscapeString(val) + `"`)
+	}
+	switch t.Data {
+	case "a":
+		b.WriteString(` rel="nofollow noopener noreferrer"`)
+	case "img":
+		if !hasSrc {
+			return "", false
+		}
+	}
+	b.WriteString(">")
+	return b.String(), true
+}
+
+// proxy возвращает адрес картинки через прокси.
+func (s Sanitizer) proxy(src string) string {
+	if s.ImageProxy == "" {
+		return src
+	}
+	return s.ImageProxy + url.QueryEscape(src)
+}
+
+// absURL приводит ссылку к абсолютной и проверяет схему.
+// Для недопустимых ссылок возвращает пустую строку.
+func absURL(raw string, base *url.URL, schemes ...string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return ""
+	}
+	if !u.IsAbs() {
+		if base == nil || u.String() == "" {
+			return ""
+		}
+		u = base.ResolveReference(u)
+	}
+	if !contains(schemes, strings.ToLower(u.Scheme)) {
+		return ""
+	}
+	return u.String()
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Text возвращает текст без разметки: сущности раскодированы,
+// пробелы схлопнуты, абзацы разделены пробелом.
+func Text(src string) string {
+	z := html.NewTokenizer(strings.NewReader(src))
+	var b strings.Builder
+	skip := 0
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(b.String()), " ")
+		case html.TextToken:
+			if skip == 0 {
+				b.Write(z.Text())
+			}
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			name, _ := z.TagName()
+			switch {
+			case dropped[string(name)] && tt == html.StartTagToken:
+				skip++
+			case dropped[string(name)] && tt == html.EndTagToken && skip > 0:
+				skip--
+			case blocks[string(name)]:
+				b.WriteByte(' ')
+			}
+		}
+	}
+}
+
+// Summary сокращает текст до n символов по границе слова
+// и добавляет многоточие. При n < 1 текст не сокращается.
+func Summary(text string, n int) string {
+	if n < 1 || utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	r := []rune(text)[:n]
+	// не обрываем слово, если пробел не слишком далеко
+	if i := lastSpace(r); i > n/2 {
+		r = r[:i]
+	}
+	cut := strings.TrimRightFunc(string(r), func(c rune) bool {
+		return unicode.IsSpace(c) || unicode.IsPunct(c)
+	})
+	return cut + "…"
+}
+
+func lastSpace(r []rune) int {
+	for i := len(r) - 1; i >= 0; i-- {
+		if unicode.IsSpace(r[i]) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,78 @@
+package sanitize
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/")
+	s := Sanitizer{ImageProxy: "/api/v1/image?url="}
+	tests := []struct {
+		name, in, want string
+	}{
+		{"абзацы и код", "<p>Текст</p><pre><code>x := 1 &lt; 2</code></pre>",
+			"<p>Текст</p><pre><code>x := 1 &lt; 2</code></pre>"},
+		{"ссылка", `<a href="/post" onclick="alert(1)" class="x">пост</a>`,
+			`<a href="https://example.com/post" rel="nofollow noopener noreferrer">пост</a>`},
+		{"javascript в ссылке", `<a href="javascript:alert(1)">x</a>`,
+			`<a rel="nofollow noopener noreferrer">x</a>`},
+		{"картинка через прокси", `<img src="img/1.png" alt="a&quot;b" width="10">`,
+			`<img src="/api/v1/image?url=https%3A%2F%2Fexample.com%2Fblog%2Fimg%2F1.png" alt="a&#34;b">`},
+		{"картинка без адреса", `<img src="data:image/png;base64,AAAA">`, ""},
+		{"скрипт и стиль", `<p>a<script>alert("x")</script><style>p{}</style>b</p>`, "<p>ab</p>"},
+		{"неизвестные теги", `<div><span>текст</span></div>`, "текст"},
+		{"незакрытые теги", `<ul><li>один<li>два`, "<ul><li>один</li><li>два</li></ul>"},
+		{"лишний закрывающий тег", `a</p>b`, "ab"},
+		{"сущности", "a&nbsp;&amp;&nbsp;b", "a\u00a0&amp;\u00a0b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.HTML(tt.in, base); got != tt.want {
+				t.Errorf("получено %q, ожидалось %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLWithoutProxy(t *testing.T) {
+	got := Sanitizer{}.HTML(`<img src="https://example.com/1.png">`, nil)
+	if want := `<img src="https://example.com/1.png">`; got != want {
+		t.Errorf("получено %q, ожидалось %q", got, want)
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"<p>Первый</p><p>второй&nbsp;абзац</p>", "Первый второй абзац"},
+		{"a &amp; b &lt;c&gt; &#8212; &quot;d&quot;", `a & b <c> — "d"`},
+		{"<b>жир</b>ный<br>текст", "жирный текст"},
+		{"<script>var x = '<p>'</script>текст", "текст"},
+		{"  много\n\tпробелов  ", "много пробелов"},
+	}
+	for _, tt := range tests {
+		if got := Text(tt.in); got != tt.want {
+			t.Errorf("Text(%q) = %q, ожидалось %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"короткий текст", 100, "короткий текст"},
+		{"короткий текст", 0, "короткий текст"},
+		{"Первое слово, второе слово", 16, "Первое слово…"},
+		{"Оченьдлинноеслово", 5, "Очень…"},
+	}
+	for _, tt := range tests {
+		if got := Summary(tt.in, tt.n); got != tt.want {
+			t.Errorf("Summary(%q, %d) = %q, ожидалось %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
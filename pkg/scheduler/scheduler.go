@@ -0,0 +1,225 @@
+// Пакет scheduler периодически опрашивает RSS-ленты. Ленты можно
+// добавлять, изменять и удалять без перезапуска приложения.
+//
+// Каждая лента опрашивается со своим интервалом. К паузам добавляется
+// случайный разброс, чтобы ленты не опрашивались одновременно, а после
+// ошибок пауза растёт экспоненциально до MaxBackoff.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+// ErrNotScheduled - лента не опрашивается: её нет или она выключена.
+var ErrNotScheduled = errors.New("лента не опрашивается")
+
+// FetchFunc получает новости из ленты и сохраняет их.
+type FetchFunc func(ctx context.Context, src storage.Source) error
+
+// Clock - источник времени планировщика, в тестах подменяется.
+type Clock interface {
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer - таймер, созданный Clock.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// Scheduler - планировщик опроса лент. Для каждой включённой ленты
+// работает своя горутина.
+type Scheduler struct {
+	ctx   context.Context
+	fetch FetchFunc
+
+	// Clock - источник времени, по умолчанию системные часы.
+	Clock Clock
+	// Jitter - доля паузы, на которую она случайно увеличивается
+	// или уменьшается. Первый опрос откладывается на случайное время
+	// до Jitter интервала.
+	Jitter float64
+	// BaseBackoff - пауза после первой ошибки, далее удваивается.
+	BaseBackoff time.Duration
+	// MaxBackoff - предельная пауза после ошибок.
+	MaxBackoff time.Duration
+
+	mu     sync.Mutex
+	jobs   map[int]*job
+	wg     sync.WaitGroup
+	rand   func() float64
+	randMu sync.Mutex
+}
+
+// Опрос одной ленты.
+type job struct {
+	src     storage.Source
+	cancel  context.CancelFunc
+	trigger chan struct{}
+}
+
+// New создаёт планировщик с настройками по умолчанию. Опрос всех лент
+// прекращается при отмене контекста ctx.
+func New(ctx context.Context, fetch FetchFunc) *Scheduler {
+	return &Scheduler{
+		ctx:         ctx,
+		fetch:       fetch,
+		Clock:       realClock{},
+		Jitter:      0.1,
+		BaseBackoff: time.Minute,
+		MaxBackoff:  6 * time.Hour,
+		jobs:        make(map[int]*job),
+		rand:        rand.Float64,
+	}
+}
+
+// Set запускает опрос ленты, перезапускает его, если изменились адрес
+// или интервал, и останавливает, если лента выключена. Остальные
+// изменения, например правила тегов, действуют со следующего опроса.
+func (s *Scheduler) Set(src storage.Source) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if j, ok := s.jobs[src.ID]; ok {
+		if src.Enabled && j.src.URL == src.URL && j.src.Interval == src.Interval {
+			j.src = src
+			return
+		}
+		j.cancel()
+		delete(s.jobs, src.ID)
+	}
+	if !src.Enabled {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	j := &job{src: src, cancel: cancel, trigger: make(chan struct{}, 1)}
+	s.jobs[src.ID] = j
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(ctx, j)
+	}()
+}
+
+// Remove останавливает опрос ленты.
+func (s *Scheduler) Remove(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j, ok := s.jobs[id]; ok {
+		j.cancel()
+		delete(s.jobs, id)
+	}
+}
+
+// Trigger запускает внеочередной опрос ленты, не дожидаясь конца паузы,
+// в том числе паузы после ошибок. Если опрос уже запрошен, повторный
+// вызов ничего не делает.
+func (s *Scheduler) Trigger(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return ErrNotScheduled
+	}
+	select {
+	case j.trigger <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Stop останавливает опрос всех лент и дожидается завершения горутин.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	for id, j := range s.jobs {
+		j.cancel()
+		delete(s.jobs, id)
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// run опрашивает ленту задачи j с паузой src.Interval минут, а после
+// ошибок - с растущей паузой, пока опрос не будет остановлен.
+func (s *Scheduler) run(ctx context.Context, j *job) {
+	src := s.source(j)
+	interval := time.Duration(src.Interval) * time.Minute
+	delay := time.Duration(s.random() * s.Jitter * float64(interval))
+	failures := 0
+	for {
+		t := s.Clock.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C():
+		case <-j.trigger:
+			t.Stop()
+		}
+
+		// Set мог изменить ленту, не перезапуская опрос
+		src = s.source(j)
+		err := s.fetch(ctx, src)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			failures++
+			delay = s.backoff(failures)
+			log.Printf("ошибка опроса ленты %s (%d подряд), следующая попытка через %v: %v",
+				src.URL, failures, delay, err)
+		} else {
+			failures = 0
+			delay = interval
+		}
+		delay = s.jitter(delay)
+	}
+}
+
+// source возвращает текущее описание ленты задачи j.
+func (s *Scheduler) source(j *job) storage.Source {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return j.src
+}
+
+// backoff возвращает паузу после failures ошибок подряд.
+func (s *Scheduler) backoff(failures int) time.Duration {
+	d := s.BaseBackoff
+	for i := 1; i < failures && d < s.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.MaxBackoff {
+		d = s.MaxBackoff
+	}
+	return d
+}
+
+// jitter случайно изменяет паузу не больше чем на Jitter её длины.
+func (s *Scheduler) jitter(d time.Duration) time.Duration {
+	return d + time.Duration((2*s.random()-1)*s.Jitter*float64(d))
+}
+
+func (s *Scheduler) random() float64 {
+	s.randMu.Lock()
+	defer s.randMu.Unlock()
+	return s.rand()
+}
+
+// Системные часы.
+type realClock struct{}
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.t.C }
+func (t realTimer) Stop() bool          { return t.t.Stop() }
@@ -0,0 +1,230 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+// Часы, время которых двигает тест.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	c  chan time.Time
+	at time.Time
+	fc *fakeClock
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{c: make(chan time.Time, 1), at: c.now.Add(d), fc: c}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.fc.mu.Lock()
+	defer t.fc.mu.Unlock()
+	for i, x := range t.fc.timers {
+		if x == t {
+			t.fc.timers = append(t.fc.timers[:i], t.fc.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Advance сдвигает время и срабатывает таймеры, срок которых наступил.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	active := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			active = append(active, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	c.timers = active
+}
+
+// wait дожидается, пока опрос ленты не встанет на паузу со сроком d.
+func (c *fakeClock) wait(t *testing.T, d time.Duration) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		c.mu.Lock()
+		ok := len(c.timers) == 1 && c.timers[0].at.Sub(c.now) == d
+		c.mu.Unlock()
+		if ok {
+			return
+		}
+	}
+	t.Fatalf("опрос не встал на паузу %v", d)
+}
+
+// newTestScheduler создаёт планировщик без разброса пауз на поддельных часах.
+// Опросы отправляются в канал, ошибка опроса берётся из errs.
+func newTestScheduler(errs ...error) (*Scheduler, *fakeClock, chan storage.Source) {
+	fetched := make(chan storage.Source, 10)
+	var mu sync.Mutex
+	s := New(context.Background(), func(ctx context.Context, src storage.Source) error {
+		fetched <- src
+		mu.Lock()
+		defer mu.Unlock()
+		if len(errs) == 0 {
+			return nil
+		}
+		err := errs[0]
+		errs = errs[1:]
+		return err
+	})
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	s.Clock = clock
+	s.Jitter = 0
+	return s, clock, fetched
+}
+
+func expectFetch(t *testing.T, fetched <-chan storage.Source) storage.Source {
+	t.Helper()
+	select {
+	case src := <-fetched:
+		return src
+	case <-time.After(time.Second):
+		t.Fatal("лента не опрошена")
+	}
+	return storage.Source{}
+}
+
+func expectNoFetch(t *testing.T, fetched <-chan storage.Source) {
+	t.Helper()
+	select {
+	case src := <-fetched:
+		t.Fatalf("лишний опрос ленты %q", src.URL)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestSchedulerInterval(t *testing.T) {
+	s, clock, fetched := newTestScheduler()
+	defer s.Stop()
+
+	src := storage.Source{ID: 1, URL: "http://a/rss", Interval: 10, Enabled: true}
+	s.Set(src)
+	expectFetch(t, fetched)
+
+	clock.wait(t, 10*time.Minute)
+	clock.Advance(9 * time.Minute)
+	expectNoFetch(t, fetched)
+	clock.Advance(time.Minute)
+	expectFetch(t, fetched)
+
+	// изменение названия не перезапускает опрос, смена адреса - перезапускает сразу
+	clock.wait(t, 10*time.Minute)
+	src.Name = "A"
+	s.Set(src)
+	expectNoFetch(t, fetched)
+	src.URL = "http://b/rss"
+	s.Set(src)
+	if got := expectFetch(t, fetched); got.URL != src.URL {
+		t.Fatalf("после изменения опрошена %q", got.URL)
+	}
+
+	s.Remove(1)
+	s.Set(storage.Source{ID: 2, URL: "http://c/rss", Interval: 10})
+	clock.Advance(time.Hour)
+	expectNoFetch(t, fetched)
+}
+
+func TestSchedulerUpdateRules(t *testing.T) {
+	s, clock, fetched := newTestScheduler()
+	defer s.Stop()
+
+	src := storage.Source{ID: 1, URL: "http://a/rss", Interval: 10, Enabled: true}
+	s.Set(src)
+	expectFetch(t, fetched)
+
+	// правила тегов меняются без перезапуска опроса
+	// и действуют со следующего опроса
+	clock.wait(t, 10*time.Minute)
+	src.TagRules = []storage.TagRule{{Tag: "go", Keywords: []string{"golang"}}}
+	s.Set(src)
+	expectNoFetch(t, fetched)
+	clock.Advance(10 * time.Minute)
+	got := expectFetch(t, fetched)
+	if len(got.TagRules) != 1 || got.TagRules[0].Tag != "go" {
+		t.Fatalf("опрошена лента с правилами %+v, ожидались %+v", got.TagRules, src.TagRules)
+	}
+}
+
+func TestSchedulerBackoff(t *testing.T) {
+	failure := errors.New("503")
+	s, clock, fetched := newTestScheduler(failure, failure, failure, failure, nil)
+	s.BaseBackoff = time.Minute
+	s.MaxBackoff = 3 * time.Minute
+	defer s.Stop()
+
+	s.Set(storage.Source{ID: 1, URL: "http://a/rss", Interval: 10, Enabled: true})
+	expectFetch(t, fetched)
+	// паузы после ошибок: 1, 2, затем не больше MaxBackoff
+	for _, d := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		clock.wait(t, d)
+		clock.Advance(d)
+		expectFetch(t, fetched)
+	}
+	// после успешного опроса - снова обычный интервал
+	clock.wait(t, 10*time.Minute)
+}
+
+func TestSchedulerTrigger(t *testing.T) {
+	s, clock, fetched := newTestScheduler()
+	defer s.Stop()
+
+	if err := s.Trigger(1); !errors.Is(err, ErrNotScheduled) {
+		t.Fatalf("Trigger() для неизвестной ленты = %v", err)
+	}
+	s.Set(storage.Source{ID: 1, URL: "http://a/rss", Interval: 10, Enabled: true})
+	expectFetch(t, fetched)
+	clock.wait(t, 10*time.Minute)
+
+	if err := s.Trigger(1); err != nil {
+		t.Fatal(err)
+	}
+	expectFetch(t, fetched)
+	// после внеочередного опроса отсчёт интервала начинается заново
+	clock.wait(t, 10*time.Minute)
+}
+
+func TestJitter(t *testing.T) {
+	s := New(context.Background(), nil)
+	s.Jitter = 0.1
+	for _, tt := range []struct {
+		rand float64
+		want time.Duration
+	}{
+		{0, 9 * time.Minute},
+		{0.5, 10 * time.Minute},
+		{1, 11 * time.Minute},
+	} {
+		s.rand = func() float64 { return tt.rand }
+		if got := s.jitter(10 * time.Minute); got != tt.want {
+			t.Errorf("jitter при rand = %v: %v, ожидалось %v", tt.rand, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,448 @@
+// Пакет service содержит логику работы с новостями и комментариями,
+// общую для HTTP, gRPC и GraphQL API.
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"Skillfactory-APIGateway/censorship"
+	dbComments "Skillfactory-APIGateway/comments/storage"
+	"Skillfactory-APIGateway/pkg/events"
+	"Skillfactory-APIGateway/pkg/ingest"
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+// Адрес сервиса цензуры по умолчанию.
+const DefaultCensorshipURL = "http://localhost:8082/check"
+
+// Количество последних событий, которые хранятся для возобновления потока.
+const eventHistory = 500
+
+// Размер страницы новостей по умолчанию и наибольший.
+const (
+	PageSize    = 10
+	MaxPageSize = 100
+)
+
+// Число новостей в исходящей ленте RSS/Atom/JSON Feed.
+const FeedSize = 50
+
+// Таймауты обращений к хранилищам при получении детальной новости.
+const (
+	postTimeout     = 3 * time.Second
+	commentsTimeout = 2 * time.Second
+)
+
+// Ошибки проверки комментария цензурой.
+var (
+	ErrForbiddenContent      = errors.New("комментарий содержит запрещённые слова")
+	ErrCensorshipUnavailable = errors.New("сервис цензуры недоступен")
+)
+
+// ErrSourceDisabled - выключенную ленту нельзя опросить.
+var ErrSourceDisabled = errors.New("лента выключена")
+
+// Service - сервис новостей и комментариев.
+type Service struct {
+	db            NewsStore
+	dbComments    CommentStore
+	censorshipURL string
+	client        *http.Client
+
+	bus       *events.Bus
+	scheduler Scheduler
+	pipeline  Pipeline
+}
+
+// NewsStore - хранилище новостей и лент, реализуется storage.DB.
+type NewsStore interface {
+	News(n int) ([]storage.Post, error)
+	SearchPosts(ctx context.Context, f storage.Filter, s storage.Sort, limit, offset int) ([]storage.Post, storage.Pagination, error)
+	PostDetal(ctx context.Context, id int) (storage.Post, error)
+	AddCommentCount(ctx context.Context, newsID, delta int) error
+	FilteredNews(ctx context.Context, f storage.Filter, n int) ([]storage.Post, error)
+	Sources(ctx context.Context) ([]storage.Source, error)
+	Source(ctx context.Context, id int) (storage.Source, error)
+	CreateSource(ctx context.Context, src storage.Source) (storage.Source, error)
+	UpdateSource(ctx context.Context, src storage.Source) (storage.Source, error)
+	DeleteSource(ctx context.Context, id int) error
+	Tags(ctx context.Context) ([]storage.Tag, error)
+}
+
+// CommentStore - хранилище комментариев, реализуется comments/storage.DB.
+type CommentStore interface {
+	AllComments(ctx context.Context, newsID int) ([]dbComments.Comment, error)
+	CommentsByNews(ctx context.Context, newsIDs []int) (map[int][]dbComments.Comment, error)
+	AddComment(ctx context.Context, c dbComments.Comment, actor string) (int, error)
+	EditComment(ctx context.Context, c dbComments.Comment, actor string) (dbComments.Comment, error)
+	DeleteComment(ctx context.Context, c dbComments.Comment, actor, reason string) (int, error)
+	Audit(ctx context.Context, f dbComments.AuditFilter) ([]dbComments.AuditEntry, error)
+}
+
+// Pipeline - конвейер сбора новостей.
+type Pipeline interface {
+	Metrics() []ingest.StageMetrics
+}
+
+// Scheduler - планировщик опроса лент, которому сервис
+// сообщает об их изменении.
+type Scheduler interface {
+	Set(src storage.Source)
+	Remove(id int)
+	Trigger(id int) error
+}
+
+// Конструктор сервиса.
+func New(db NewsStore, dbComments CommentStore) *Service {
+	return &Service{
+		db:            db,
+		dbComments:    dbComments,
+		censorshipURL: DefaultCensorshipURL,
+		client:        &http.Client{Timeout: 5 * time.Second},
+		bus:           events.NewBus(eventHistory),
+	}
+}
+
+// Detailed - новость с комментариями.
+type Detailed struct {
+	Post     storage.Post
+	Comments []dbComments.Comment
+	// CommentsErr не пуста, если комментарии получить не удалось.
+	CommentsErr error
+}
+
+// News возвращает n последних новостей.
+func (s *Service) News(ctx context.Context, n int) ([]storage.Post, error) {
+	return s.db.News(n)
+}
+
+// Latest возвращает страницу из limit новостей, подходящих под фильтр,
+// в порядке sort. Нулевой limit - PageSize, больший MaxPageSize - ошибка.
+func (s *Service) Latest(ctx context.Context, f storage.Filter, sort storage.Sort, page, limit int) ([]storage.Post, storage.Pagination, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit == 0 {
+		limit = PageSize
+	}
+	if limit < 0 || limit > MaxPageSize {
+		return nil, storage.Pagination{}, fmt.Errorf("%w: размер страницы должен быть от 1 до %d",
+			storage.ErrInvalidArgument, MaxPageSize)
+	}
+	return s.db.SearchPosts(ctx, f, sort, limit, (page-1)*limit)
+}
+
+// SetScheduler подключает планировщик опроса лент.
+func (s *Service) SetScheduler(sch Scheduler) {
+	s.scheduler = sch
+}
+
+// SetPipeline подключает конвейер сбора новостей.
+func (s *Service) SetPipeline(p Pipeline) {
+	s.pipeline = p
+}
+
+// IngestMetrics возвращает показатели стадий конвейера сбора новостей.
+func (s *Service) IngestMetrics() []ingest.StageMetrics {
+	if s.pipeline == nil {
+		return []ingest.StageMetrics{}
+	}
+	return s.pipeline.Metrics()
+}
+
+// Sources возвращает ленты-источники новостей.
+func (s *Service) Sources(ctx context.Context) ([]storage.Source, error) {
+	return s.db.Sources(ctx)
+}
+
+// Tags возвращает теги с числом новостей.
+func (s *Service) Tags(ctx context.Context) ([]storage.Tag, error) {
+	return s.db.Tags(ctx)
+}
+
+// Source возвращает ленту по id.
+func (s *Service) Source(ctx context.Context, id int) (storage.Source, error) {
+	return s.db.Source(ctx, id)
+}
+
+// CreateSource добавляет ленту и, если она включена, сразу начинает её опрос.
+func (s *Service) CreateSource(ctx context.Context, src storage.Source) (storage.Source, error) {
+	src, err := s.db.CreateSource(ctx, src)
+	if err != nil {
+		return storage.Source{}, err
+	}
+	if s.scheduler != nil {
+		s.scheduler.Set(src)
+	}
+	return src, nil
+}
+
+// UpdateSource сохраняет изменения ленты и передаёт их планировщику.
+func (s *Service) UpdateSource(ctx context.Context, src storage.Source) (storage.Source, error) {
+	src, err := s.db.UpdateSource(ctx, src)
+	if err != nil {
+		return storage.Source{}, err
+	}
+	if s.scheduler != nil {
+		s.scheduler.Set(src)
+	}
+	return src, nil
+}
+
+// ImportResult - итог импорта лент.
+type ImportResult struct {
+	// Created - добавленные ленты.
+	Created []storage.Source `json:"created"`
+	// Skipped - адреса лент, которые уже были в БД.
+	Skipped []string `json:"skipped"`
+}
+
+// ImportSources добавляет включённые ленты с интервалом опроса
+// storage.DefaultInterval. Ленты, которые уже есть в БД, пропускаются.
+// Если хотя бы одна лента некорректна, ничего не добавляется.
+func (s *Service) ImportSources(ctx context.Context, sources []storage.Source) (ImportResult, error) {
+	for i := range sources {
+		sources[i].Interval = storage.DefaultInterval
+		sources[i].Enabled = true
+		if err := sources[i].Validate(); err != nil {
+			return ImportResult{}, fmt.Errorf("лента %q: %w", sources[i].URL, err)
+		}
+	}
+	res := ImportResult{Created: []storage.Source{}, Skipped: []string{}}
+	for _, src := range sources {
+		created, err := s.CreateSource(ctx, src)
+		if errors.Is(err, storage.ErrConflict) {
+			res.Skipped = append(res.Skipped, src.URL)
+			continue
+		}
+		if err != nil {
+			return res, fmt.Errorf("лента %s: %w", src.URL, err)
+		}
+		res.Created = append(res.Created, created)
+	}
+	return res, nil
+}
+
+// FetchSource запускает внеочередной опрос ленты.
+func (s *Service) FetchSource(ctx context.Context, id int) error {
+	src, err := s.db.Source(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !src.Enabled || s.scheduler == nil {
+		return ErrSourceDisabled
+	}
+	if err := s.scheduler.Trigger(id); err != nil {
+		return fmt.Errorf("%w: %v", ErrSourceDisabled, err)
+	}
+	return nil
+}
+
+// DeleteSource удаляет ленту и прекращает её опрос.
+func (s *Service) DeleteSource(ctx context.Context, id int) error {
+	if err := s.db.DeleteSource(ctx, id); err != nil {
+		return err
+	}
+	if s.scheduler != nil {
+		s.scheduler.Remove(id)
+	}
+	return nil
+}
+
+// Feed возвращает последние новости для исходящей ленты.
+func (s *Service) Feed(ctx context.Context, f storage.Filter) ([]storage.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, postTimeout)
+	defer cancel()
+	return s.db.FilteredNews(ctx, f, FeedSize)
+}
+
+// Detailed возвращает новость с комментариями.
+// Новость и комментарии запрашиваются параллельно, каждый запрос
+// со своим таймаутом. Если комментарии недоступны, новость всё равно
+// возвращается, а ошибка сохраняется в CommentsErr.
+func (s *Service) Detailed(ctx context.Context, id int) (Detailed, error) {
+	var (
+		wg  sync.WaitGroup
+		d   Detailed
+		err error
+	)
+	wg.Add(2)
+	// Получаем новость
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(ctx, postTimeout)
+		defer cancel()
+		d.Post, err = s.db.PostDetal(ctx, id)
+	}()
+	// Получаем комментарии
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(ctx, commentsTimeout)
+		defer cancel()
+		d.Comments, d.CommentsErr = s.dbComments.AllComments(ctx, id)
+		hideDeleted(d.Comments)
+	}()
+	wg.Wait()
+
+	if err != nil {
+		return Detailed{}, err
+	}
+	if d.CommentsErr != nil {
+		d.Comments = []dbComments.Comment{}
+	}
+	return d, nil
+}
+
+// Post возвращает новость по id без комментариев.
+func (s *Service) Post(ctx context.Context, id int) (storage.Post, error) {
+	return s.db.PostDetal(ctx, id)
+}
+
+// Comments возвращает комментарии к новости.
+func (s *Service) Comments(ctx context.Context, newsID int) ([]dbComments.Comment, error) {
+	comments, err := s.dbComments.AllComments(ctx, newsID)
+	hideDeleted(comments)
+	return comments, err
+}
+
+// CommentsByNews возвращает комментарии к нескольким новостям,
+// сгруппированные по id новости.
+func (s *Service) CommentsByNews(ctx context.Context, newsIDs []int) (map[int][]dbComments.Comment, error) {
+	comments, err := s.dbComments.CommentsByNews(ctx, newsIDs)
+	for _, list := range comments {
+		hideDeleted(list)
+	}
+	return comments, err
+}
+
+// hideDeleted заменяет текст удалённых комментариев на RemovedContent,
+// текст остаётся только в журнале действий.
+func hideDeleted(comments []dbComments.Comment) {
+	for i := range comments {
+		if comments[i].Deleted {
+			comments[i].Content = dbComments.RemovedContent
+		}
+	}
+}
+
+// AddComment проверяет комментарий цензурой и сохраняет его.
+func (s *Service) AddComment(ctx context.Context, c dbComments.Comment) (dbComments.Comment, error) {
+	allowed, err := s.checkCensorship(ctx, c.Content)
+	if err != nil {
+		return dbComments.Comment{}, fmt.Errorf("%w: %v", ErrCensorshipUnavailable, err)
+	}
+	if !allowed {
+		return dbComments.Comment{}, ErrForbiddenContent
+	}
+	c.ID, err = s.dbComments.AddComment(ctx, c, Actor(ctx))
+	if err != nil {
+		return dbComments.Comment{}, err
+	}
+	if err := s.db.AddCommentCount(ctx, c.NewsID, 1); err != nil {
+		log.Println("не удалось обновить число комментариев:", err)
+	}
+	s.bus.Publish(events.Event{Type: events.CommentCreated, Comment: c})
+	return c, nil
+}
+
+// EditComment проверяет новый текст комментария цензурой и сохраняет его.
+func (s *Service) EditComment(ctx context.Context, c dbComments.Comment) (dbComments.Comment, error) {
+	allowed, err := s.checkCensorship(ctx, c.Content)
+	if err != nil {
+		return dbComments.Comment{}, fmt.Errorf("%w: %v", ErrCensorshipUnavailable, err)
+	}
+	if !allowed {
+		return dbComments.Comment{}, ErrForbiddenContent
+	}
+	return s.dbComments.EditComment(ctx, c, Actor(ctx))
+}
+
+// DeleteComment помечает комментарий удалённым по причине reason.
+func (s *Service) DeleteComment(ctx context.Context, id int, reason string) error {
+	c := dbComments.Comment{ID: id}
+	newsID, err := s.dbComments.DeleteComment(ctx, c, Actor(ctx), reason)
+	if err != nil {
+		return err
+	}
+	c.NewsID = newsID
+	if err := s.db.AddCommentCount(ctx, newsID, -1); err != nil {
+		log.Println("не удалось обновить число комментариев:", err)
+	}
+	s.bus.Publish(events.Event{Type: events.CommentDeleted, Comment: c})
+	return nil
+}
+
+// CommentAudit возвращает записи журнала действий с комментариями.
+func (s *Service) CommentAudit(ctx context.Context, f dbComments.AuditFilter) ([]dbComments.AuditEntry, error) {
+	return s.dbComments.Audit(ctx, f)
+}
+
+type actorKey struct{}
+
+// WithActor возвращает контекст, в котором действия с комментариями
+// записываются в журнал от имени actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor возвращает автора действий из контекста, по умолчанию "anonymous".
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return "anonymous"
+}
+
+func (s *Service) checkCensorship(ctx context.Context, comment string) (bool, error) {
+	reqBody, err := json.Marshal(censorship.Request{Comment: comment})
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.censorshipURL, bytes.NewReader(reqBody))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("сервис цензуры вернул статус %d", resp.StatusCode)
+	}
+
+	var result censorship.Response
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return false, err
+	}
+	return result.Allowed, nil
+}
+
+// Publish рассылает подписчикам только что добавленные новости.
+func (s *Service) Publish(posts []storage.Post) {
+	s.bus.PublishPosts(posts)
+}
+
+// Subscribe подписывает на события о новых новостях и комментариях, начиная
+// с события, следующего за lastEventID (0 - только новые события).
+// Подписку нужно закрыть, когда поток больше не нужен.
+func (s *Service) Subscribe(lastEventID uint64) *events.Subscription {
+	return s.bus.Subscribe(lastEventID)
+}
+
+// SubscribeAll подписывает на все новые события без пропусков,
+// см. events.Bus.SubscribeAll.
+func (s *Service) SubscribeAll() *events.Subscription {
+	return s.bus.SubscribeAll()
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	dbComments "Skillfactory-APIGateway/comments/storage"
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+// Хранилище новостей с одной новостью. Остальные методы не нужны.
+type fakeNews struct {
+	NewsStore
+	post storage.Post
+	err  error
+}
+
+func (f *fakeNews) PostDetal(ctx context.Context, id int) (storage.Post, error) {
+	return f.post, f.err
+}
+
+// Хранилище комментариев, которое отвечает через delay или при отмене
+// контекста, смотря что наступит раньше.
+type fakeComments struct {
+	CommentStore
+	comments []dbComments.Comment
+	err      error
+	delay    time.Duration
+}
+
+func (f *fakeComments) AllComments(ctx context.Context, newsID int) ([]dbComments.Comment, error) {
+	select {
+	case <-time.After(f.delay):
+		return f.comments, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestDetailed(t *testing.T) {
+	post := storage.Post{ID: 1, Title: "Go 1.24"}
+	errComments := errors.New("БД комментариев недоступна")
+	errPost := errors.New("новость не найдена")
+
+	tests := []struct {
+		name         string
+		news         *fakeNews
+		comments     *fakeComments
+		wantErr      error
+		wantComments int
+		wantCommErr  error
+	}{
+		{
+			name:         "новость и комментарии",
+			news:         &fakeNews{post: post},
+			comments:     &fakeComments{comments: []dbComments.Comment{{ID: 1, NewsID: 1, Content: "Отлично"}}},
+			wantComments: 1,
+		},
+		{
+			name:        "комментарии не успели",
+			news:        &fakeNews{post: post},
+			comments:    &fakeComments{delay: 2 * commentsTimeout},
+			wantCommErr: context.DeadlineExceeded,
+		},
+		{
+			name:        "ошибка комментариев",
+			news:        &fakeNews{post: post},
+			comments:    &fakeComments{err: errComments},
+			wantCommErr: errComments,
+		},
+		{
+			name:     "ошибка новости",
+			news:     &fakeNews{err: errPost},
+			comments: &fakeComments{},
+			wantErr:  errPost,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			d, err := New(tt.news, tt.comments).Detailed(context.Background(), 1)
+			if elapsed := time.Since(start); elapsed > commentsTimeout+time.Second {
+				t.Errorf("ответ через %v, таймаут комментариев %v", elapsed, commentsTimeout)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ошибка %v, ожидалась %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if d.Post.ID != post.ID {
+				t.Errorf("новость %+v, ожидалась %+v", d.Post, post)
+			}
+			if !errors.Is(d.CommentsErr, tt.wantCommErr) {
+				t.Errorf("ошибка комментариев %v, ожидалась %v", d.CommentsErr, tt.wantCommErr)
+			}
+			// при ошибке комментариев возвращается пустой список, а не nil
+			if d.Comments == nil || len(d.Comments) != tt.wantComments {
+				t.Errorf("комментарии %v, ожидалось %d", d.Comments, tt.wantComments)
+			}
+		})
+	}
+}
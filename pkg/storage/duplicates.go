@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"Skillfactory-APIGateway/pkg/dedup"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Окно поиска похожих новостей по времени публикации, секунды.
+const duplicateWindow = 3 * 24 * 60 * 60
+
+// findDuplicate возвращает номер основной новости, повтором которой
+// является post, или 0. Совпадать должны каноническая ссылка, GUID
+// в виде ссылки или simhash текста с точностью до dedup.MaxDistance.
+func (db *DB) findDuplicate(ctx context.Context, post Post) (int, error) {
+	// GUID сравнивается только в виде ссылки: короткие GUID вроде
+	// номера записи совпадают у разных сайтов
+	guid := ""
+	if u, err := url.Parse(post.GUID); err == nil && u.IsAbs() && u.Host != "" {
+		guid = post.GUID
+	}
+	var id int
+	err := db.Pool.QueryRow(ctx, `
+	SELECT id FROM news
+	WHERE duplicate_of IS NULL AND link <> $3
+		AND (($1 <> '' AND canonical_url = $1) OR ($2 <> '' AND guid = $2))
+	ORDER BY id LIMIT 1`,
+		post.CanonicalURL, guid, post.Link,
+	).Scan(&id)
+	if err == nil || !errors.Is(err, pgx.ErrNoRows) {
+		return id, err
+	}
+	if post.Simhash == 0 {
+		return 0, nil
+	}
+
+	// число различающихся битов считается через bit(64)
+	err = db.Pool.QueryRow(ctx, `
+	SELECT id FROM news
+	WHERE duplicate_of IS NULL AND simhash <> 0 AND link <> $4
+		AND pub_time BETWEEN $2::bigint - $5 AND $2::bigint + $5
+		AND length(replace((simhash # $1)::bit(64)::text, '0', '')) <= $3
+	ORDER BY id LIMIT 1`,
+		int64(post.Simhash), post.PubTime, dedup.MaxDistance, post.Link, duplicateWindow,
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+// attachAlternates добавляет к новостям их повторы из других лент.
+func (db *DB) attachAlternates(ctx context.Context, posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]int, len(posts))
+	index := make(map[int]int, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+		index[p.ID] = i
+	}
+	rows, err := db.Pool.Query(ctx, `
+	SELECT n.duplicate_of, n.id, n.link, COALESCE(n.source_id, 0), COALESCE(s.name, '')
+	FROM news n LEFT JOIN sources s ON s.id = n.source_id
+	WHERE n.duplicate_of = ANY($1)
+	ORDER BY n.id`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var of int
+		var a Alternate
+		if err := rows.Scan(&of, &a.ID, &a.Link, &a.SourceID, &a.SourceName); err != nil {
+			return err
+		}
+		i := index[of]
+		posts[i].Alternates = append(posts[i].Alternates, a)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+)
+
+// ExpiredNews возвращает до limit id новостей, опубликованных раньше
+// before, с id больше after, по возрастанию id.
+func (db *DB) ExpiredNews(ctx context.Context, before int64, after, limit int) ([]int, error) {
+	rows, err := db.Pool.Query(ctx, `
+	SELECT id FROM news
+	WHERE pub_time < $1 AND id > $2
+	ORDER BY id
+	LIMIT $3`, before, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// RemoveNews удаляет новости и возвращает их число. При archive
+// новости вместе с тегами сначала копируются в news_archive.
+// Если удаляется основная новость, а её повторы из других лент остаются,
+// основной становится самый ранний из них, остальные - его повторами.
+func (db *DB) RemoveNews(ctx context.Context, ids []int, archive bool) (int, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	if archive {
+		_, err = tx.Exec(ctx, `
+		INSERT INTO news_archive (news_id, pub_time, data)
+		SELECT n.id, n.pub_time, to_jsonb(n) || jsonb_build_object('tags',
+			ARRAY(SELECT t.name FROM news_tags nt JOIN tags t ON t.id = nt.tag_id
+				WHERE nt.news_id = n.id ORDER BY t.name))
+		FROM news n WHERE n.id = ANY($1)`, ids)
+		if err != nil {
+			return 0, err
+		}
+	}
+	_, err = tx.Exec(ctx, `
+	WITH survivors AS (
+		SELECT id, first_value(id) OVER (PARTITION BY duplicate_of ORDER BY pub_time, id) AS head
+		FROM news
+		WHERE duplicate_of = ANY($1) AND NOT id = ANY($1)
+	)
+	UPDATE news n SET duplicate_of = NULLIF(s.head, s.id)
+	FROM survivors s
+	WHERE n.id = s.id`, ids)
+	if err != nil {
+		return 0, err
+	}
+	tag, err := tx.Exec(ctx, "DELETE FROM news WHERE id = ANY($1);", ids)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), tx.Commit(ctx)
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrSourceNotFound - лента с указанным id не найдена.
+var ErrSourceNotFound = errors.New("лента не найдена")
+
+// Интервал опроса ленты по умолчанию, минуты.
+const DefaultInterval = 25
+
+// Source - лента RSS, из которой получены публикации.
+type Source struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// URL - адрес ленты.
+	URL string `json:"url"`
+	// Link - адрес сайта из описания ленты.
+	Link string `json:"link"`
+	// Category - категория ленты, вложенные папки разделяются "/".
+	Category string `json:"category"`
+	// Interval - период опроса ленты, минуты.
+	Interval int `json:"interval"`
+	// Enabled - лента опрашивается.
+	Enabled bool `json:"enabled"`
+	// TagRules - правила тегирования новостей ленты по ключевым словам.
+	TagRules []TagRule `json:"tag_rules"`
+	// Posts - число публикаций из ленты в БД.
+	Posts int `json:"posts"`
+}
+
+// Validate проверяет ленту перед сохранением.
+func (src *Source) Validate() error {
+	u, err := url.Parse(src.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url должен быть абсолютным http(s) адресом", ErrInvalidArgument)
+	}
+	if src.Interval < 1 {
+		return fmt.Errorf("%w: интервал опроса должен быть не меньше минуты", ErrInvalidArgument)
+	}
+	return validateTagRules(src.TagRules)
+}
+
+// Столбцы ленты, порядок соответствует scanSource.
+const sourceColumns = `s.id, s.name, s.url, s.link, s.category, s.interval, s.enabled, s.tag_rules,
+	(SELECT count(*) FROM news n WHERE n.source_id = s.id)
+	FROM sources s`
+
+func scanSource(row pgx.Row) (Source, error) {
+	var src Source
+	err := row.Scan(&src.ID, &src.Name, &src.URL, &src.Link, &src.Category, &src.Interval, &src.Enabled,
+		&src.TagRules, &src.Posts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Source{}, ErrSourceNotFound
+	}
+	return src, err
+}
+
+// SaveSource сохраняет ленту после получения новостей из неё или
+// обновляет сайт уже известной ленты с тем же адресом. Название,
+// заданное вручную, не перезаписывается. Возвращает ленту с заполненным ID.
+func (db *DB) SaveSource(ctx context.Context, src Source) (Source, error) {
+	err := db.Pool.QueryRow(ctx, `
+	INSERT INTO sources(name, url, link)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (url) DO UPDATE SET
+		name = CASE WHEN sources.name = '' THEN EXCLUDED.name ELSE sources.name END,
+		link = EXCLUDED.link
+	RETURNING id, name`,
+		src.Name, src.URL, src.Link,
+	).Scan(&src.ID, &src.Name)
+	return src, err
+}
+
+// SeedSources добавляет ленты с адресами из urls, только если в БД ещё нет
+// ни одной ленты. Так удалённые администратором ленты не возвращаются
+// после перезапуска.
+func (db *DB) SeedSources(ctx context.Context, urls []string, interval int) error {
+	_, err := db.Pool.Exec(ctx, `
+	INSERT INTO sources(url, interval)
+	SELECT u, $2 FROM unnest($1::text[]) AS u
+	WHERE NOT EXISTS (SELECT 1 FROM sources)
+	ON CONFLICT (url) DO NOTHING`, urls, interval)
+	return err
+}
+
+// Sources возвращает все ленты с числом публикаций из каждой.
+func (db *DB) Sources(ctx context.Context) ([]Source, error) {
+	rows, err := db.Pool.Query(ctx, "SELECT "+sourceColumns+" ORDER BY s.category, s.name, s.id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sources := []Source{}
+	for rows.Next() {
+		src, err := scanSource(rows)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, src)
+	}
+	return sources, rows.Err()
+}
+
+// Source возвращает ленту по id.
+func (db *DB) Source(ctx context.Context, id int) (Source, error) {
+	return scanSource(db.Pool.QueryRow(ctx, "SELECT "+sourceColumns+" WHERE s.id = $1", id))
+}
+
+// CreateSource добавляет ленту. Если лента с таким адресом
+// уже есть, возвращается ErrConflict.
+func (db *DB) CreateSource(ctx context.Context, src Source) (Source, error) {
+	if err := src.Validate(); err != nil {
+		return Source{}, err
+	}
+	err := db.Pool.QueryRow(ctx, `
+	INSERT INTO sources(name, url, category, interval, enabled, tag_rules)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id`,
+		src.Name, src.URL, src.Category, src.Interval, src.Enabled, tagRules(src.TagRules),
+	).Scan(&src.ID)
+	if err != nil {
+		return Source{}, sourceError(err)
+	}
+	return src, nil
+}
+
+// UpdateSource сохраняет изменённые адрес, название, категорию,
+// интервал, признак опроса и правила тегирования ленты.
+func (db *DB) UpdateSource(ctx context.Context, src Source) (Source, error) {
+	if err := src.Validate(); err != nil {
+		return Source{}, err
+	}
+	tag, err := db.Pool.Exec(ctx, `
+	UPDATE sources SET name = $2, url = $3, category = $4, interval = $5, enabled = $6,
+		tag_rules = $7
+	WHERE id = $1`,
+		src.ID, src.Name, src.URL, src.Category, src.Interval, src.Enabled, tagRules(src.TagRules),
+	)
+	if err != nil {
+		return Source{}, sourceError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return Source{}, ErrSourceNotFound
+	}
+	return db.Source(ctx, src.ID)
+}
+
+// DeleteSource удаляет ленту. Новости из неё остаются в БД без источника.
+func (db *DB) DeleteSource(ctx context.Context, id int) error {
+	tag, err := db.Pool.Exec(ctx, "DELETE FROM sources WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSourceNotFound
+	}
+	return nil
+}
+
+// tagRules заменяет nil пустым списком: столбец NOT NULL.
+func tagRules(rules []TagRule) []TagRule {
+	if rules == nil {
+		return []TagRule{}
+	}
+	return rules
+}
+
+// sourceError заменяет нарушение уникальности адреса на ErrConflict.
+func sourceError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return fmt.Errorf("%w: лента с таким адресом уже есть", ErrConflict)
+	}
+	return err
+}
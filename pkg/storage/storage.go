@@ -10,9 +10,17 @@ import (
 	"log"
 	"os"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Ошибки хранилища, по которым API выбирает статус ответа.
+var (
+	ErrNotFound        = errors.New("публикация не найдена")
+	ErrInvalidArgument = errors.New("некорректный аргумент")
+	ErrConflict        = errors.New("запись уже существует")
+)
+
 // База данных.
 type DB struct {
 	Pool *pgxpool.Pool
@@ -27,11 +35,77 @@ type Pagination struct {
 
 // Публикация, получаемая из RSS.
 type Post struct {
-	ID      int    // номер записи
-	Title   string // заголовок публикации
-	Content string // содержание публикации
-	PubTime int64  // время публикации
-	Link    string // ссылка на источник
+	ID         int    // номер записи
+	Title      string // заголовок публикации
+	Content    string // содержание публикации
+	PubTime    int64  // время публикации
+	Link       string // ссылка на источник
+	SourceID   int    // номер ленты, из которой получена публикация
+	SourceName string // название ленты
+	SourceURL  string // адрес сайта ленты
+
+	GUID       string      `json:"GUID,omitempty"`       // идентификатор записи в ленте
+	Author     string      `json:"Author,omitempty"`     // автор
+	Categories []string    `json:"Categories,omitempty"` // рубрики из ленты
+	Enclosures []Enclosure `json:"Enclosures,omitempty"` // вложения: аудио, видео, картинки
+	Image      string      `json:"Image,omitempty"`      // адрес картинки-обложки
+	RawHTML    string      `json:"RawHTML,omitempty"`    // исходный HTML содержания
+	// ContentHTML - очищенный HTML содержания, безопасный для вывода.
+	ContentHTML string `json:"ContentHTML,omitempty"`
+	// Summary - начало текста для списков новостей.
+	Summary string `json:"Summary,omitempty"`
+	// CanonicalURL - ссылка без параметров отслеживания, по ней
+	// находятся повторы из других лент.
+	CanonicalURL string `json:"CanonicalURL,omitempty"`
+	// Simhash - хеш текста для поиска похожих новостей.
+	Simhash uint64 `json:"-"`
+	// RawDate - дата публикации из ленты как есть, для сообщений
+	// о неразобранной дате.
+	RawDate string `json:"-"`
+	// DuplicateOf - номер основной новости, если эта - её повтор.
+	DuplicateOf int `json:"DuplicateOf,omitempty"`
+	// Alternates - та же новость из других лент.
+	Alternates []Alternate `json:"Alternates,omitempty"`
+	// Tags - теги из рубрик ленты и правил тегирования.
+	Tags []string `json:"Tags,omitempty"`
+	// CommentCount - число комментариев.
+	CommentCount int `json:"CommentCount,omitempty"`
+}
+
+// Alternate - повтор новости из другой ленты.
+type Alternate struct {
+	ID         int    `json:"ID"`
+	Link       string `json:"Link"`
+	SourceID   int    `json:"SourceID,omitempty"`
+	SourceName string `json:"SourceName,omitempty"`
+}
+
+// Enclosure - вложение публикации.
+type Enclosure struct {
+	URL    string `json:"URL"`
+	Type   string `json:"Type,omitempty"`
+	Length int64  `json:"Length,omitempty"`
+}
+
+// Столбцы публикации вместе с данными источника, порядок
+// соответствует scanPost.
+const postColumns = `n.id, n.title, n.content, n.pub_time, n.link,
+	COALESCE(n.source_id, 0), COALESCE(s.name, ''), COALESCE(NULLIF(s.link, ''), s.url, ''),
+	n.guid, n.author, n.categories, n.enclosures, n.image, n.raw_html,
+	n.content_html, n.summary, n.canonical_url, COALESCE(n.duplicate_of, 0),
+	ARRAY(SELECT t.name FROM news_tags nt JOIN tags t ON t.id = nt.tag_id
+		WHERE nt.news_id = n.id ORDER BY t.name), n.comments
+	FROM news n LEFT JOIN sources s ON s.id = n.source_id`
+
+// scanPost читает публикацию из строки результата запроса по postColumns.
+func scanPost(row pgx.Row) (Post, error) {
+	var p Post
+	err := row.Scan(&p.ID, &p.Title, &p.Content, &p.PubTime, &p.Link,
+		&p.SourceID, &p.SourceName, &p.SourceURL,
+		&p.GUID, &p.Author, &p.Categories, &p.Enclosures, &p.Image, &p.RawHTML,
+		&p.ContentHTML, &p.Summary, &p.CanonicalURL, &p.DuplicateOf, &p.Tags,
+		&p.CommentCount)
+	return p, err
 }
 
 // конфигурация подключения к PostgreSQL
@@ -46,6 +120,22 @@ type sqlPostgres struct {
 
 // Запись в БД новых новостей
 func New() (*DB, error) {
+	db, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	// Выполнение SQL-скрипта
+	if err := db.initSchema(); err != nil {
+		return nil, fmt.Errorf("ошибка инициализации схемы: %v", err)
+	}
+
+	return db, nil
+}
+
+// Connect подключается к БД без инициализации схемы,
+// данные в БД сохраняются. Используется командами CLI.
+func Connect() (*DB, error) {
 	// Чтение конфигурации базы данных файла
 	b, err := ioutil.ReadFile("./sqlPostgres.json")
 	if err != nil {
@@ -66,16 +156,7 @@ func New() (*DB, error) {
 	if err != nil {
 		return nil, err
 	}
-	db := DB{
-		Pool: pool,
-	}
-
-	// Выполнение SQL-скрипта
-	if err := db.initSchema(); err != nil {
-		return nil, fmt.Errorf("ошибка инициализации схемы: %v", err)
-	}
-
-	return &db, nil
+	return &DB{Pool: pool}, nil
 }
 
 // initSchema выполняет SQL-скрипт из файла для инициализации БД
@@ -95,26 +176,68 @@ func (db *DB) initSchema() error {
 	return nil
 }
 
-// вставка новой записи
-func (db *DB) StoreNews(news []Post) error {
-	var id int //проверка, что записалось
+// StoreNews сохраняет новости и возвращает только те из них,
+// которых ещё не было в БД, с заполненным ID. Повторы новостей из других
+// лент сохраняются со ссылкой на основную новость и не возвращаются.
+func (db *DB) StoreNews(news []Post) ([]Post, error) {
+	var added []Post
 	for _, post := range news {
-		_, err := db.Pool.Exec(context.Background(), `
-		INSERT INTO news(title, content, pub_time, link)
-		VALUES ($1, $2, $3, $4)
+		dup, err := db.findDuplicate(context.Background(), post)
+		if err != nil {
+			return added, err
+		}
+		post.DuplicateOf = dup
+		// пустые списки, а не NULL: столбцы NOT NULL
+		categories, enclosures := post.Categories, post.Enclosures
+		if categories == nil {
+			categories = []string{}
+		}
+		if enclosures == nil {
+			enclosures = []Enclosure{}
+		}
+		err = db.Pool.QueryRow(context.Background(), `
+		INSERT INTO news(title, content, pub_time, link, source_id,
+			guid, author, categories, enclosures, image, raw_html,
+			content_html, summary, canonical_url, simhash, duplicate_of)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), $6, $7, $8, $9, $10, $11, $12, $13,
+			$14, $15, NULLIF($16, 0))
+		ON CONFLICT (link) DO NOTHING
         RETURNING id`,
 			post.Title,
 			post.Content,
 			post.PubTime,
 			post.Link,
-		)
+			post.SourceID,
+			post.GUID,
+			post.Author,
+			categories,
+			enclosures,
+			post.Image,
+			post.RawHTML,
+			post.ContentHTML,
+			post.Summary,
+			post.CanonicalURL,
+			int64(post.Simhash),
+			post.DuplicateOf,
+		).Scan(&post.ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// новость уже есть в БД
+			continue
+		}
 		if err != nil {
-			return err
+			return added, err
+		}
+		if err := db.saveTags(context.Background(), post.ID, post.Tags); err != nil {
+			return added, err
+		}
+		if post.DuplicateOf == 0 {
+			added = append(added, post)
 		}
-		id++
 	}
-	fmt.Printf("Добавлено %d записи с сайта %s\n", id, news[0].Link)
-	return nil
+	if len(added) > 0 {
+		fmt.Printf("Добавлено %d записи с сайта %s\n", len(added), added[0].Link)
+	}
+	return added, nil
 }
 
 // News возвращает последние новости из БД.
@@ -123,8 +246,9 @@ func (db *DB) News(n int) ([]Post, error) {
 		n = 10
 	}
 	rows, err := db.Pool.Query(context.Background(), `
-	SELECT id, title, content, pub_time, link FROM news
-	ORDER BY pub_time DESC
+	SELECT `+postColumns+`
+	WHERE n.duplicate_of IS NULL
+	ORDER BY n.pub_time DESC
 	LIMIT $1
 	`,
 		n,
@@ -132,16 +256,10 @@ func (db *DB) News(n int) ([]Post, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	var news []Post
 	for rows.Next() {
-		var p Post
-		err = rows.Scan(
-			&p.ID,
-			&p.Title,
-			&p.Content,
-			&p.PubTime,
-			&p.Link,
-		)
+		p, err := scanPost(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -157,103 +275,164 @@ func (db *DB) Close() {
 	}
 }
 
-// PostSearchILIKE Поиск по заголовку
-func (db *DB) PostSearchILIKE(pattern string, limit, offset int) ([]Post, Pagination, error) {
-	pattern = "%" + pattern + "%"
-
+// SearchPosts возвращает страницу публикаций, подходящих под фильтр,
+// в порядке s, число подходящих публикаций и страниц.
+func (db *DB) SearchPosts(ctx context.Context, f Filter, s Sort, limit, offset int) ([]Post, Pagination, error) {
+	if limit < 1 || offset < 0 {
+		return nil, Pagination{}, fmt.Errorf("%w: неверный размер или смещение страницы", ErrInvalidArgument)
+	}
 	pagination := Pagination{
 		Page:  offset/limit + 1,
 		Limit: limit,
 	}
-	row := db.Pool.QueryRow(context.Background(), "SELECT count(*) FROM news WHERE title ILIKE $1;", pattern)
-	err := row.Scan(&pagination.NumOfPages)
-
-	if pagination.NumOfPages%limit > 0 {
-		pagination.NumOfPages = pagination.NumOfPages/limit + 1
-	} else {
-		pagination.NumOfPages /= limit
-	}
-
+	row := db.Pool.QueryRow(ctx, "SELECT count(*) FROM news n WHERE "+filterSQL, f.args()...)
+	err := row.Scan(&pagination.TotalItems)
 	if err != nil {
 		return nil, Pagination{}, err
 	}
+	pagination.NumOfPages = pagination.TotalItems / limit
+	if pagination.TotalItems%limit > 0 {
+		pagination.NumOfPages++
+	}
 
-	rows, err := db.Pool.Query(context.Background(), "SELECT * FROM news WHERE title ILIKE $1 ORDER BY pub_time DESC LIMIT $2 OFFSET $3;", pattern, limit, offset)
+	rows, err := db.Pool.Query(ctx, "SELECT "+postColumns+" WHERE "+filterSQL+
+		" ORDER BY "+s.orderSQL()+" LIMIT $6 OFFSET $7;", f.args(limit, offset)...)
 	if err != nil {
 		return nil, Pagination{}, err
 	}
 	defer rows.Close()
 	var posts []Post
 	for rows.Next() {
-		var p Post
-		err = rows.Scan(&p.ID, &p.Title, &p.Content, &p.PubTime, &p.Link)
+		p, err := scanPost(rows)
 		if err != nil {
 			return nil, Pagination{}, err
 		}
 		posts = append(posts, p)
 	}
-	return posts, pagination, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, Pagination{}, err
+	}
+	rows.Close()
+	if err := db.attachAlternates(ctx, posts); err != nil {
+		return nil, Pagination{}, err
+	}
+	return posts, pagination, nil
 }
 
-// Posts Получение странице с определенным номером
-func (db *DB) Posts(Page int) ([]Post, error) {
-	if Page < 0 {
-		err := errors.New("invalid value - must be greater than zero")
-		return nil, err
+// PostDetal Получение публикаций по id
+func (db *DB) PostDetal(ctx context.Context, id int) (Post, error) {
+	if id < 1 {
+		return Post{}, fmt.Errorf("%w: id должен быть больше нуля", ErrInvalidArgument)
+	}
+	row := db.Pool.QueryRow(ctx, `
+	SELECT `+postColumns+`
+    WHERE n.id =$1;
+	`, id)
+	post, err := scanPost(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Post{}, ErrNotFound
 	}
-	rows, err := db.Pool.Query(context.Background(), `
-	SELECT * FROM news
-	ORDER BY pub_time DESC LIMIT 10 OFFSET $1
-	`,
-		Page,
-	)
 	if err != nil {
-		return nil, err
+		return Post{}, err
 	}
-	defer rows.Close()
+	posts := []Post{post}
+	if err := db.attachAlternates(ctx, posts); err != nil {
+		return Post{}, err
+	}
+	return posts[0], nil
+}
 
-	var posts []Post
-	// итерированное по результату выполнения запроса
-	// и сканирование каждой строки в переменную
-	for rows.Next() {
-		var p Post
-		err = rows.Scan(
-			&p.ID,
-			&p.Title,
-			&p.Content,
-			&p.PubTime,
-			&p.Link,
-		)
-		if err != nil {
-			return nil, err
-		}
-		// добавление переменной в массив результатов
-		posts = append(posts, p)
+// Filter - условия отбора новостей.
+type Filter struct {
+	// Search - подстрока заголовка.
+	Search string
+	// SourceID - номер ленты-источника.
+	SourceID int
+	// Tag - тег новости.
+	Tag string
+	// From и To - границы времени публикации включительно,
+	// секунды Unix; 0 - без границы.
+	From, To int64
+}
+
+// Условие отбора по Filter, параметры запроса $1 - $5 из Filter.args.
+// Повторы новостей из других лент не выводятся.
+const filterSQL = `n.duplicate_of IS NULL
+	AND ($1 = '' OR n.title ILIKE '%' || $1 || '%')
+	AND ($2 = 0 OR n.source_id = $2)
+	AND ($3 = '' OR EXISTS (SELECT 1 FROM news_tags nt JOIN tags t ON t.id = nt.tag_id
+		WHERE nt.news_id = n.id AND t.name = $3))
+	AND ($4 = 0 OR n.pub_time >= $4)
+	AND ($5 = 0 OR n.pub_time <= $5)`
 
+// args возвращает параметры запроса для filterSQL и следующие за ними.
+func (f Filter) args(more ...interface{}) []interface{} {
+	return append([]interface{}{f.Search, f.SourceID, f.Tag, f.From, f.To}, more...)
+}
+
+// Порядок новостей.
+const (
+	// SortPubTime - по времени публикации.
+	SortPubTime = "pub_time"
+	// SortRelevance - по близости заголовка к поисковому запросу. Поиск идёт
+	// только по заголовку, поэтому и ранжируется только заголовок.
+	SortRelevance = "relevance"
+	// SortComments - по числу комментариев.
+	SortComments = "comments"
+)
+
+// Sort - порядок страницы новостей.
+type Sort struct {
+	// By - SortPubTime, SortRelevance или SortComments,
+	// пустая строка - SortPubTime.
+	By string
+	// Asc - по возрастанию, иначе по убыванию.
+	Asc bool
+}
+
+// orderSQL возвращает выражение ORDER BY для запроса с filterSQL.
+// Равные новости упорядочиваются от новых к старым.
+func (s Sort) orderSQL() string {
+	dir := " DESC"
+	if s.Asc {
+		dir = " ASC"
+	}
+	switch s.By {
+	case SortRelevance:
+		return `ts_rank(to_tsvector('russian', n.title),
+			plainto_tsquery('russian', $1))` + dir + ", n.pub_time DESC, n.id DESC"
+	case SortComments:
+		return "n.comments" + dir + ", n.pub_time DESC, n.id DESC"
+	default:
+		return "n.pub_time" + dir + ", n.id" + dir
 	}
-	// ВАЖНО не забыть проверить rows.Err()
-	return posts, rows.Err()
 }
 
-// PostDetal Получение публикаций по id
-func (db *DB) PostDetal(id int) (Post, error) {
-	if id < 1 {
-		err := errors.New("invalid id - must be greater than zero")
-		return Post{}, err
+// AddCommentCount изменяет число комментариев новости на delta.
+func (db *DB) AddCommentCount(ctx context.Context, newsID, delta int) error {
+	_, err := db.Pool.Exec(ctx,
+		"UPDATE news SET comments = GREATEST(comments + $2, 0) WHERE id = $1;", newsID, delta)
+	return err
+}
+
+// FilteredNews возвращает n последних новостей, подходящих под фильтр.
+func (db *DB) FilteredNews(ctx context.Context, f Filter, n int) ([]Post, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("%w: количество новостей должно быть больше нуля", ErrInvalidArgument)
 	}
-	row := db.Pool.QueryRow(context.Background(), `
-	SELECT * FROM news 
-    WHERE id =$1;
-	`, id)
-	var post Post
-	err := row.Scan(
-		&post.ID,
-		&post.Title,
-		&post.Content,
-		&post.PubTime,
-		&post.Link)
+	rows, err := db.Pool.Query(ctx, "SELECT "+postColumns+" WHERE "+filterSQL+
+		" ORDER BY n.pub_time DESC LIMIT $6", f.args(n)...)
 	if err != nil {
-		return Post{}, err
+		return nil, err
 	}
-	return post, nil
+	defer rows.Close()
+	news := []Post{}
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		news = append(news, p)
+	}
+	return news, rows.Err()
 }
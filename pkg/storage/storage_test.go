@@ -26,7 +26,7 @@ func TestDB_News(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = db.StoreNews(posts)
+	_, err = db.StoreNews(posts)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TagRule - правило тегирования ленты: новость получает тег Tag,
+// если в её заголовке или тексте есть одно из слов Keywords.
+type TagRule struct {
+	Tag      string   `json:"tag"`
+	Keywords []string `json:"keywords"`
+}
+
+// Tag - тег с числом новостей.
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// NormalizeTag приводит тег к нижнему регистру и убирает лишние пробелы.
+func NormalizeTag(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// validateTagRules проверяет правила тегирования ленты.
+func validateTagRules(rules []TagRule) error {
+	for _, r := range rules {
+		if NormalizeTag(r.Tag) == "" {
+			return fmt.Errorf("%w: у правила тегирования не указан тег", ErrInvalidArgument)
+		}
+		if len(r.Keywords) == 0 {
+			return fmt.Errorf("%w: у правила для тега %q нет ключевых слов", ErrInvalidArgument, r.Tag)
+		}
+	}
+	return nil
+}
+
+// Tags возвращает теги с числом новостей, начиная с самых частых.
+// Повторы новостей из других лент не учитываются.
+func (db *DB) Tags(ctx context.Context) ([]Tag, error) {
+	rows, err := db.Pool.Query(ctx, `
+	SELECT t.name, count(*) FROM tags t
+	JOIN news_tags nt ON nt.tag_id = t.id
+	JOIN news n ON n.id = nt.news_id
+	WHERE n.duplicate_of IS NULL
+	GROUP BY t.name
+	ORDER BY count(*) DESC, t.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := []Tag{}
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.Name, &t.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// saveTags привязывает теги к новости, новые теги добавляются в таблицу.
+func (db *DB) saveTags(ctx context.Context, newsID int, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	_, err := db.Pool.Exec(ctx, `
+	INSERT INTO tags(name) SELECT unnest($1::text[])
+	ON CONFLICT (name) DO NOTHING`, tags)
+	if err != nil {
+		return err
+	}
+	_, err = db.Pool.Exec(ctx, `
+	INSERT INTO news_tags(news_id, tag_id)
+	SELECT $1, id FROM tags WHERE name = ANY($2)
+	ON CONFLICT DO NOTHING`, newsID, tags)
+	return err
+}
@@ -0,0 +1,296 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"Skillfactory-APIGateway/pkg/events"
+)
+
+// Заголовки запроса веб-хука.
+const (
+	HeaderEvent     = "X-GoNews-Event"
+	HeaderDelivery  = "X-GoNews-Delivery"
+	HeaderSignature = "X-GoNews-Signature"
+)
+
+// Аренда доставки: столько взятая обработчиком доставка не видна
+// другим обработчикам. Если процесс остановится посреди попытки,
+// доставка вернётся в очередь по её истечении.
+const claimLease = time.Minute
+
+// store - часть хранилища, которая нужна для доставки.
+type store interface {
+	Active(ctx context.Context, event string) ([]Subscription, error)
+	LogDelivery(ctx context.Context, d Delivery) error
+	Enqueue(ctx context.Context, pending []Pending) error
+	Claim(ctx context.Context, now, lease time.Time) (Pending, bool, error)
+	Retry(ctx context.Context, id int64, attempt int, next time.Time) error
+	Done(ctx context.Context, id int64) error
+}
+
+// Dispatcher доставляет события шины подписчикам веб-хуков.
+type Dispatcher struct {
+	store  store
+	client *http.Client
+
+	// MaxAttempts - число попыток доставки одного события.
+	MaxAttempts int
+	// BaseDelay - пауза перед второй попыткой, далее удваивается.
+	BaseDelay time.Duration
+	// MaxDelay - предельная пауза между попытками.
+	MaxDelay time.Duration
+	// Workers - число одновременных доставок.
+	Workers int
+	// PollInterval - как часто обработчики проверяют очередь,
+	// когда новых событий нет.
+	PollInterval time.Duration
+}
+
+// NewDispatcher создаёт диспетчер с настройками по умолчанию.
+func NewDispatcher(s store) *Dispatcher {
+	return &Dispatcher{
+		store:        s,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts:  5,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		Workers:      8,
+		PollInterval: time.Second,
+	}
+}
+
+// Тело запроса веб-хука.
+type payload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt int64       `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Run читает события из подписки и ставит доставки в очередь, а Workers
+// обработчиков доставляют их из очереди, пока не будет отменён контекст
+// или закрыта подписка. Чтение подписки не ждёт доставок, а подписка
+// должна быть без пропусков (events.Bus.SubscribeAll), иначе события
+// теряются ещё до очереди. Недоставленные события остаются в очереди
+// до следующего запуска.
+func (d *Dispatcher) Run(ctx context.Context, sub *events.Subscription) {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	wake := make(chan struct{}, 1)
+	for i := 0; i < d.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work(ctx, wake)
+		}()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := d.enqueue(ctx, e); err != nil {
+				log.Println("веб-хуки: не удалось поставить событие в очередь:", err)
+				continue
+			}
+			notify(wake)
+		}
+	}
+}
+
+// notify будит один из ожидающих обработчиков, не дожидаясь его.
+func notify(wake chan<- struct{}) {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue ставит в очередь доставку события каждой подходящей подписке.
+func (d *Dispatcher) enqueue(ctx context.Context, e events.Event) error {
+	subs, err := d.store.Active(ctx, e.Type)
+	if err != nil {
+		return err
+	}
+	var pending []Pending
+	for _, s := range subs {
+		if !matches(s, e) {
+			continue
+		}
+		p := payload{
+			ID:        newDeliveryID(),
+			Event:     e.Type,
+			CreatedAt: time.Now().Unix(),
+		}
+		if e.Type == events.PostCreated {
+			p.Data = e.Post
+		} else {
+			p.Data = e.Comment
+		}
+		body, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		pending = append(pending, Pending{
+			Subscription: s,
+			DeliveryID:   p.ID,
+			Event:        p.Event,
+			Payload:      string(body),
+		})
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	return d.store.Enqueue(ctx, pending)
+}
+
+// matches проверяет фильтр подписки по ключевому слову.
+func matches(s Subscription, e events.Event) bool {
+	if s.Keyword == "" {
+		return true
+	}
+	var text string
+	switch e.Type {
+	case events.PostCreated:
+		text = e.Post.Title + " " + e.Post.Content
+	default:
+		text = e.Comment.Content
+	}
+	return strings.Contains(strings.ToLower(text), strings.ToLower(s.Keyword))
+}
+
+// work забирает из очереди доставки, время которых наступило, и выполняет
+// их. Когда очередь пуста, ждёт нового события или PollInterval.
+func (d *Dispatcher) work(ctx context.Context, wake chan struct{}) {
+	t := time.NewTicker(d.PollInterval)
+	defer t.Stop()
+	for ctx.Err() == nil {
+		now := time.Now()
+		p, ok, err := d.store.Claim(ctx, now, now.Add(claimLease))
+		if err != nil && ctx.Err() == nil {
+			log.Println("веб-хуки: не удалось получить доставку из очереди:", err)
+		}
+		if ok {
+			// в очереди могут быть ещё доставки - пусть их заберёт другой обработчик
+			notify(wake)
+			d.attempt(ctx, p)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-wake:
+		case <-t.C:
+		}
+	}
+}
+
+// attempt выполняет очередную попытку доставки и записывает её в журнал.
+// Неуспешная доставка возвращается в очередь с экспоненциальной паузой,
+// пока не исчерпаны MaxAttempts.
+func (d *Dispatcher) attempt(ctx context.Context, p Pending) {
+	p.Attempt++
+	start := time.Now()
+	status, err := d.send(ctx, p)
+	if ctx.Err() != nil {
+		// попытка прервана остановкой и будет повторена после аренды
+		return
+	}
+	rec := Delivery{
+		SubscriptionID: p.Subscription.ID,
+		DeliveryID:     p.DeliveryID,
+		Event:          p.Event,
+		Payload:        p.Payload,
+		Attempt:        p.Attempt,
+		StatusCode:     status,
+		Success:        err == nil,
+		DurationMS:     time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	if logErr := d.store.LogDelivery(ctx, rec); logErr != nil {
+		log.Println("веб-хуки: не удалось записать журнал доставки:", logErr)
+	}
+
+	if err == nil || p.Attempt >= d.MaxAttempts {
+		err = d.store.Done(ctx, p.ID)
+	} else {
+		err = d.store.Retry(ctx, p.ID, p.Attempt, time.Now().Add(d.backoff(p.Attempt)))
+	}
+	if err != nil {
+		log.Println("веб-хуки: не удалось обновить очередь:", err)
+	}
+}
+
+// backoff возвращает паузу после попытки attempt: BaseDelay,
+// удваивающийся с каждой попыткой, но не больше MaxDelay.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.BaseDelay
+	for i := 1; i < attempt && delay < d.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.MaxDelay {
+		delay = d.MaxDelay
+	}
+	return delay
+}
+
+// send выполняет одну попытку доставки. Успешной считается попытка
+// с ответом 2xx.
+func (d *Dispatcher) send(ctx context.Context, p Pending) (int, error) {
+	body := []byte(p.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoNews-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, p.Event)
+	req.Header.Set(HeaderDelivery, p.DeliveryID)
+	req.Header.Set(HeaderSignature, Sign(p.Subscription.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("получатель ответил статусом %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign возвращает подпись тела запроса в формате "sha256=<hex>".
+// Получатель вычисляет HMAC-SHA256 тела со своим ключом и сравнивает.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newDeliveryID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
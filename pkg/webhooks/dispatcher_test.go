@@ -0,0 +1,245 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Skillfactory-APIGateway/pkg/events"
+	"Skillfactory-APIGateway/pkg/storage"
+)
+
+// Хранилище в памяти для тестов диспетчера.
+type memStore struct {
+	mu          sync.Mutex
+	subs        []Subscription
+	activeDelay time.Duration
+	deliveries  []Delivery
+	// очередь: доставка и время следующей попытки
+	queue    map[int64]Pending
+	next     map[int64]time.Time
+	lastID   int64
+	enqueued int
+}
+
+func (m *memStore) Active(ctx context.Context, event string) ([]Subscription, error) {
+	time.Sleep(m.activeDelay)
+	return m.subs, nil
+}
+
+func (m *memStore) LogDelivery(ctx context.Context, d Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, d)
+	return nil
+}
+
+func (m *memStore) Enqueue(ctx context.Context, pending []Pending) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.queue == nil {
+		m.queue = make(map[int64]Pending)
+		m.next = make(map[int64]time.Time)
+	}
+	for _, p := range pending {
+		m.lastID++
+		p.ID = m.lastID
+		m.queue[p.ID] = p
+		m.next[p.ID] = time.Time{}
+		m.enqueued++
+	}
+	return nil
+}
+
+func (m *memStore) Claim(ctx context.Context, now, lease time.Time) (Pending, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, p := range m.queue {
+		if !m.next[id].After(now) {
+			m.next[id] = lease
+			return p, true, nil
+		}
+	}
+	return Pending{}, false, nil
+}
+
+func (m *memStore) Retry(ctx context.Context, id int64, attempt int, next time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p := m.queue[id]
+	p.Attempt = attempt
+	m.queue[id] = p
+	m.next[id] = next
+	return nil
+}
+
+func (m *memStore) Done(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.queue, id)
+	delete(m.next, id)
+	return nil
+}
+
+// counts возвращает число поставленных в очередь доставок,
+// записей журнала и доставок, оставшихся в очереди.
+func (m *memStore) counts() (enqueued, logged, queued int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.enqueued, len(m.deliveries), len(m.queue)
+}
+
+func TestDispatcherRetriesAndSigns(t *testing.T) {
+	var calls int32
+	received := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if !hmac.Equal([]byte(r.Header.Get(HeaderSignature)), []byte(Sign("key", body))) {
+			t.Errorf("неверная подпись %q", r.Header.Get(HeaderSignature))
+		}
+		if r.Header.Get(HeaderEvent) != events.PostCreated {
+			t.Errorf("%s = %q", HeaderEvent, r.Header.Get(HeaderEvent))
+		}
+		// первые две попытки неуспешны
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received <- body
+	}))
+	defer srv.Close()
+
+	st := &memStore{subs: []Subscription{
+		{ID: 1, URL: srv.URL, Secret: "key", Keyword: "go"},
+		{ID: 2, URL: srv.URL, Secret: "key", Keyword: "rust"},
+	}}
+	d := NewDispatcher(st)
+	d.BaseDelay = time.Millisecond
+	d.PollInterval = time.Millisecond
+
+	bus := events.NewBus(10)
+	sub := bus.SubscribeAll()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx, sub)
+		close(done)
+	}()
+	bus.PublishPosts([]storage.Post{{ID: 7, Title: "Go 1.23"}})
+
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("веб-хук не доставлен")
+	}
+	// ответ получателя записывается в журнал после возврата из обработчика
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		st.mu.Lock()
+		n := len(st.deliveries)
+		st.mu.Unlock()
+		if n == 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if len(st.deliveries) != 3 {
+		t.Fatalf("записей в журнале %d, ожидалось 3", len(st.deliveries))
+	}
+	for i, rec := range st.deliveries {
+		if rec.SubscriptionID != 1 {
+			t.Errorf("доставка подписке %d, фильтр по слову не сработал", rec.SubscriptionID)
+		}
+		if rec.Attempt != i+1 || rec.Success != (i == 2) {
+			t.Errorf("попытка %d: %+v", i+1, rec)
+		}
+	}
+	if st.deliveries[0].DeliveryID != st.deliveries[2].DeliveryID {
+		t.Error("повторные попытки должны иметь тот же идентификатор доставки")
+	}
+}
+
+func TestDispatcherSlowReceiver(t *testing.T) {
+	// получатель отвечает медленно и с ошибкой, все обработчики заняты
+	release := make(chan struct{})
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-release:
+		case <-time.After(20 * time.Millisecond):
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	st := &memStore{subs: []Subscription{{ID: 1, URL: srv.URL, Secret: "key"}}, activeDelay: time.Millisecond}
+	d := NewDispatcher(st)
+	d.Workers = 2
+	d.MaxAttempts = 1
+	d.PollInterval = time.Millisecond
+
+	bus := events.NewBus(0)
+	sub := bus.SubscribeAll()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx, sub)
+		close(done)
+	}()
+
+	// одна пачка событий больше буфера обычной подписки (64), а каждое
+	// событие ставится в очередь дольше, чем публикуется
+	const n = 200
+	posts := make([]storage.Post, n)
+	for i := range posts {
+		posts[i] = storage.Post{ID: i + 1, Title: "Go"}
+	}
+	bus.PublishPosts(posts)
+
+	// все доставки попадают в очередь, пока получатель ещё занят
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		enqueued, logged, _ := st.counts()
+		if enqueued == n {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("в очереди %d доставок из %d, попыток %d", enqueued, n, logged)
+		}
+	}
+	for deadline := time.Now().Add(20 * time.Second); time.Now().Before(deadline); {
+		if _, logged, _ := st.counts(); logged == n {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	enqueued, logged, queued := st.counts()
+	if enqueued != n || logged != n || queued != 0 {
+		t.Fatalf("в очереди было %d доставок, попыток %d, осталось %d, ожидалось %d событий",
+			enqueued, logged, queued, n)
+	}
+	seen := make(map[string]bool)
+	for _, rec := range st.deliveries {
+		if rec.Success || rec.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("неожиданная попытка %+v", rec)
+		}
+		seen[rec.DeliveryID] = true
+	}
+	if len(seen) != n {
+		t.Errorf("попыток доставки разных событий %d, ожидалось %d", len(seen), n)
+	}
+}
@@ -0,0 +1,287 @@
+// Пакет webhooks - исходящие веб-хуки о новых новостях и комментариях.
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"time"
+
+	"Skillfactory-APIGateway/pkg/events"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Ошибки хранилища веб-хуков.
+var (
+	ErrNotFound        = errors.New("подписка не найдена")
+	ErrInvalidArgument = errors.New("некорректная подписка")
+)
+
+// Типы событий, на которые можно подписаться.
+var eventTypes = map[string]bool{
+	events.PostCreated:    true,
+	events.CommentCreated: true,
+	events.CommentDeleted: true,
+}
+
+// Subscription - подписка на события.
+type Subscription struct {
+	ID     int      `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Keyword - если задано, доставляются только события,
+	// в тексте которых есть это слово.
+	Keyword string `json:"keyword,omitempty"`
+	// Secret - ключ подписи HMAC-SHA256.
+	Secret    string `json:"secret,omitempty"`
+	Active    bool   `json:"active"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// Delivery - запись журнала доставки, одна на каждую попытку.
+type Delivery struct {
+	ID             int64  `json:"id"`
+	SubscriptionID int    `json:"webhook_id"`
+	DeliveryID     string `json:"delivery_id"`
+	Event          string `json:"event"`
+	Payload        string `json:"payload"`
+	Attempt        int    `json:"attempt"`
+	StatusCode     int    `json:"status_code"`
+	Error          string `json:"error,omitempty"`
+	Success        bool   `json:"success"`
+	DurationMS     int64  `json:"duration_ms"`
+	CreatedAt      int64  `json:"created_at"`
+}
+
+// Pending - доставка события подписчику, ожидающая в очереди.
+type Pending struct {
+	ID           int64
+	Subscription Subscription
+	DeliveryID   string
+	Event        string
+	Payload      string
+	// Attempt - число сделанных попыток.
+	Attempt int
+}
+
+// Store - хранилище подписок и журнала доставки в PostgreSQL.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// New создаёт хранилище в БД новостей и инициализирует его схему.
+func New(pool *pgxpool.Pool) (*Store, error) {
+	s := Store{pool: pool}
+	// Чтение файла для создания схемы базы данных
+	sqlBytes, err := ioutil.ReadFile("./schemaWebhooks.sql")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать файл schemaWebhooks.sql: %v", err)
+	}
+	_, err = pool.Exec(context.Background(), string(sqlBytes))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка инициализации схемы веб-хуков: %v", err)
+	}
+	return &s, nil
+}
+
+// Validate проверяет подписку перед сохранением.
+func (sub *Subscription) Validate() error {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url должен быть абсолютным http(s) адресом", ErrInvalidArgument)
+	}
+	if len(sub.Events) == 0 {
+		return fmt.Errorf("%w: не указаны события", ErrInvalidArgument)
+	}
+	for _, e := range sub.Events {
+		if !eventTypes[e] {
+			return fmt.Errorf("%w: неизвестное событие %q", ErrInvalidArgument, e)
+		}
+	}
+	return nil
+}
+
+// Create сохраняет подписку. Если ключ подписи не задан, он генерируется.
+func (s *Store) Create(ctx context.Context, sub Subscription) (Subscription, error) {
+	if err := sub.Validate(); err != nil {
+		return Subscription{}, err
+	}
+	if sub.Secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return Subscription{}, err
+		}
+		sub.Secret = hex.EncodeToString(b)
+	}
+	err := s.pool.QueryRow(ctx, `
+	INSERT INTO webhooks (url, events, keyword, secret)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, active, created_at`,
+		sub.URL, sub.Events, sub.Keyword, sub.Secret,
+	).Scan(&sub.ID, &sub.Active, &sub.CreatedAt)
+	if err != nil {
+		return Subscription{}, err
+	}
+	return sub, nil
+}
+
+// List возвращает все подписки без ключей подписи.
+func (s *Store) List(ctx context.Context) ([]Subscription, error) {
+	rows, err := s.pool.Query(ctx, `
+	SELECT id, url, events, keyword, active, created_at FROM webhooks
+	ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	subs := []Subscription{}
+	for rows.Next() {
+		var sub Subscription
+		err = rows.Scan(&sub.ID, &sub.URL, &sub.Events, &sub.Keyword, &sub.Active, &sub.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// Delete удаляет подписку вместе с её журналом доставки.
+func (s *Store) Delete(ctx context.Context, id int) error {
+	tag, err := s.pool.Exec(ctx, "DELETE FROM webhooks WHERE id = $1;", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Active возвращает активные подписки на событие, вместе с ключами подписи.
+func (s *Store) Active(ctx context.Context, event string) ([]Subscription, error) {
+	rows, err := s.pool.Query(ctx, `
+	SELECT id, url, events, keyword, secret, active, created_at FROM webhooks
+	WHERE active AND $1 = ANY(events)`, event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var subs []Subscription
+	for rows.Next() {
+		var sub Subscription
+		err = rows.Scan(&sub.ID, &sub.URL, &sub.Events, &sub.Keyword, &sub.Secret, &sub.Active, &sub.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// LogDelivery записывает попытку доставки в журнал.
+func (s *Store) LogDelivery(ctx context.Context, d Delivery) error {
+	_, err := s.pool.Exec(ctx, `
+	INSERT INTO webhook_deliveries
+		(webhook_id, delivery_id, event, payload, attempt, status_code, error, success, duration_ms)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		d.SubscriptionID, d.DeliveryID, d.Event, d.Payload, d.Attempt,
+		d.StatusCode, d.Error, d.Success, d.DurationMS,
+	)
+	return err
+}
+
+// Deliveries возвращает последние limit записей журнала доставки подписки.
+func (s *Store) Deliveries(ctx context.Context, subscriptionID, limit int) ([]Delivery, error) {
+	var exists bool
+	err := s.pool.QueryRow(ctx, "SELECT true FROM webhooks WHERE id = $1;", subscriptionID).Scan(&exists)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.pool.Query(ctx, `
+	SELECT id, webhook_id, delivery_id, event, payload, attempt, status_code, error, success, duration_ms, created_at
+	FROM webhook_deliveries
+	WHERE webhook_id = $1
+	ORDER BY id DESC
+	LIMIT $2`, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		err = rows.Scan(&d.ID, &d.SubscriptionID, &d.DeliveryID, &d.Event, &d.Payload, &d.Attempt,
+			&d.StatusCode, &d.Error, &d.Success, &d.DurationMS, &d.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Enqueue ставит доставки в очередь, попытки можно делать сразу.
+func (s *Store) Enqueue(ctx context.Context, pending []Pending) error {
+	b := &pgx.Batch{}
+	for _, p := range pending {
+		b.Queue(`
+		INSERT INTO webhook_queue (webhook_id, delivery_id, event, payload)
+		VALUES ($1, $2, $3, $4)`,
+			p.Subscription.ID, p.DeliveryID, p.Event, p.Payload,
+		)
+	}
+	return s.pool.SendBatch(ctx, b).Close()
+}
+
+// Claim забирает из очереди доставку, время попытки которой наступило
+// к now, и откладывает её до lease, чтобы её не взял другой обработчик.
+// Если таких доставок нет, ok == false.
+func (s *Store) Claim(ctx context.Context, now, lease time.Time) (p Pending, ok bool, err error) {
+	err = s.pool.QueryRow(ctx, `
+	UPDATE webhook_queue q SET next_at = $2
+	FROM webhooks w
+	WHERE w.id = q.webhook_id AND q.id = (
+		SELECT id FROM webhook_queue
+		WHERE next_at <= $1
+		ORDER BY next_at, id
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING q.id, q.delivery_id, q.event, q.payload, q.attempt, w.id, w.url, w.secret`,
+		now.UnixMilli(), lease.UnixMilli(),
+	).Scan(&p.ID, &p.DeliveryID, &p.Event, &p.Payload, &p.Attempt,
+		&p.Subscription.ID, &p.Subscription.URL, &p.Subscription.Secret)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Pending{}, false, nil
+	}
+	if err != nil {
+		return Pending{}, false, err
+	}
+	return p, true, nil
+}
+
+// Retry возвращает доставку в очередь: сделано attempt попыток,
+// следующая - не раньше next.
+func (s *Store) Retry(ctx context.Context, id int64, attempt int, next time.Time) error {
+	_, err := s.pool.Exec(ctx, `
+	UPDATE webhook_queue SET attempt = $2, next_at = $3 WHERE id = $1`,
+		id, attempt, next.UnixMilli(),
+	)
+	return err
+}
+
+// Done удаляет завершённую доставку из очереди.
+func (s *Store) Done(ctx context.Context, id int64) error {
+	_, err := s.pool.Exec(ctx, "DELETE FROM webhook_queue WHERE id = $1;", id)
+	return err
+}